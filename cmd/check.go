@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/aeciopires/updateGit/internal/common"
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/aeciopires/updateGit/internal/git"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// checkResult is the result of one pre-flight check
+type checkResult struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+var (
+	// configLoadError is the error returned by loadConfig. It is reported by the check command.
+	configLoadError error
+
+	// checkCmd verifies the configuration and the environment before running other commands
+	checkCmd = &cobra.Command{
+		Use:   "check",
+		Short: "Check the configuration and the environment before updating",
+		Long: `Run pre-flight checks before the pull command:
+
+  - the config file is parseable and valid
+  - the base directory exists and is readable
+  - the git binary is available
+  - the filter patterns compile
+  - the backup directory is writable (if backup is enabled)
+  - at least one repository is found in the base directory
+  - the repositories with HTTPS remotes have a credential helper
+
+The exit code is 0 only if all checks pass.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Annotations:  map[string]string{skipConfigLoadError: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			results := runChecks()
+
+			failed := 0
+			for _, result := range results {
+				printCheckResult(result)
+				if !result.Passed {
+					failed++
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d checks failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+)
+
+// init initializes the check command
+func init() {
+	rootCmd.AddCommand(checkCmd)
+}
+
+// runChecks executes all pre-flight checks in order
+func runChecks() []checkResult {
+	baseDir := config.Properties.Git.BaseDir
+	if baseDir == "" {
+		baseDir = "./git_repos"
+	}
+
+	results := []checkResult{checkConfigFile()}
+
+	baseDirResult := checkBaseDir(baseDir)
+	results = append(results, baseDirResult, checkGitBinary(), checkFilterPatterns())
+
+	if config.Properties.Backup.Enabled {
+		results = append(results, checkBackupDir(config.Properties.Backup.Directory))
+	}
+
+	// Discovering repositories needs a readable base directory
+	if baseDirResult.Passed {
+		results = append(results, checkRepositories(baseDir), checkCredentialHelper(baseDir))
+	} else {
+		results = append(results, checkResult{Name: "Repositories discoverable", Detail: "base directory is not readable"})
+	}
+
+	return results
+}
+
+// checkConfigFile reports the result of the configuration loading
+func checkConfigFile() checkResult {
+	result := checkResult{Name: "Configuration valid"}
+	if configLoadError != nil {
+		result.Detail = configLoadError.Error()
+		return result
+	}
+
+	result.Passed = true
+	result.Detail = "using defaults, environment variables and flags"
+	if configFile := viper.ConfigFileUsed(); configFile != "" && common.FileExists(configFile) {
+		result.Detail = configFile
+	}
+	return result
+}
+
+// checkBaseDir verifies the base directory exists and is readable
+func checkBaseDir(baseDir string) checkResult {
+	result := checkResult{Name: "Base directory readable", Detail: baseDir}
+	if err := validateBaseDir(baseDir); err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	if _, err := os.ReadDir(baseDir); err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+
+	result.Passed = true
+	return result
+}
+
+// checkGitBinary verifies the required commands are in the PATH with their minimum versions
+func checkGitBinary() checkResult {
+	result := checkResult{Name: "Git binary available"}
+	for command, minVersion := range config.CommandsToCheck {
+		path, err := exec.LookPath(command)
+		if err != nil {
+			result.Detail = err.Error()
+			return result
+		}
+		if err := common.CheckMinimumVersion(command, minVersion); err != nil {
+			result.Detail = err.Error()
+			return result
+		}
+		result.Detail = path
+	}
+	if err := checkGitConfigEnvSupport(); err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+
+	result.Passed = true
+	return result
+}
+
+// checkFilterPatterns verifies the filter can be created with the configured patterns
+func checkFilterPatterns() checkResult {
+	result := checkResult{Name: "Filter patterns compile"}
+	if _, err := initializeFilter(); err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+
+	result.Passed = true
+	return result
+}
+
+// checkBackupDir verifies a file can be created inside the backup directory
+func checkBackupDir(backupDir string) checkResult {
+	result := checkResult{Name: "Backup directory writable", Detail: backupDir}
+	if err := os.MkdirAll(backupDir, config.PermissionDir); err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+
+	file, err := os.CreateTemp(backupDir, ".check-*")
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	result.Passed = true
+	return result
+}
+
+// checkRepositories verifies at least one repository is found in the base directory
+func checkRepositories(baseDir string) checkResult {
+	result := checkResult{Name: "Repositories discoverable"}
+	repositories, err := git.FindRepositoriesRecursive(baseDir, config.Properties.Git.MaxDepth, config.Properties.Git.IncludeSubmoduleRepos)
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	if len(repositories) == 0 {
+		result.Detail = "no git repository found in " + baseDir
+		return result
+	}
+
+	result.Passed = true
+	result.Detail = fmt.Sprintf("%d repositories found", len(repositories))
+	return result
+}
+
+// checkCredentialHelper verifies git has a credential helper for the repositories with HTTPS remotes,
+// as git can not ask for login/password on servers without a terminal
+func checkCredentialHelper(baseDir string) checkResult {
+	result := checkResult{Name: "Credential helper for HTTPS remotes"}
+	repositories, err := git.FindRepositoriesRecursive(baseDir, config.Properties.Git.MaxDepth, config.Properties.Git.IncludeSubmoduleRepos)
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+
+	var missing []string
+	httpsCount := 0
+	for _, repo := range repositories {
+		if !git.HasHTTPSRemote(repo.Path) {
+			continue
+		}
+		httpsCount++
+		if !git.CredentialHelperConfigured(repo.Path) {
+			missing = append(missing, repo.Name)
+		}
+	}
+	if len(missing) > 0 {
+		result.Detail = fmt.Sprintf("no credential helper for %s. Use --git-credential-helper", strings.Join(missing, ", "))
+		return result
+	}
+
+	result.Passed = true
+	result.Detail = fmt.Sprintf("%d repositories with HTTPS remotes", httpsCount)
+	return result
+}
+
+// printCheckResult prints a green checkmark or a red X followed by the check name and details
+func printCheckResult(result checkResult) {
+	mark, color := "✔", "\033[32m"
+	if !result.Passed {
+		mark, color = "✘", "\033[31m"
+	}
+	if config.Properties.Output.Color {
+		mark = color + mark + "\033[0m"
+	}
+
+	if result.Detail != "" {
+		fmt.Printf("%s %s: %s\n", mark, result.Name, result.Detail)
+		return
+	}
+	fmt.Printf("%s %s\n", mark, result.Name)
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/aeciopires/updateGit/internal/gittest"
+)
+
+func TestRunChecks(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup configures the failure and returns the base directory
+		setup        func(t *testing.T) string
+		failedChecks []string
+		detail       string
+	}{
+		{
+			name:  "all checks pass",
+			setup: newCheckBaseDir,
+		},
+		{
+			name: "invalid configuration",
+			setup: func(t *testing.T) string {
+				configLoadError = errors.New("invalid value of git.max_depth")
+				t.Cleanup(func() { configLoadError = nil })
+				return newCheckBaseDir(t)
+			},
+			failedChecks: []string{"Configuration valid"},
+			detail:       "invalid value of git.max_depth",
+		},
+		{
+			name: "missing base directory",
+			setup: func(t *testing.T) string {
+				return filepath.Join(t.TempDir(), "missing")
+			},
+			failedChecks: []string{"Base directory readable", "Repositories discoverable"},
+			detail:       "directory does not exist",
+		},
+		{
+			name: "git binary not in the PATH",
+			setup: func(t *testing.T) string {
+				baseDir := newCheckBaseDir(t)
+				t.Setenv("PATH", t.TempDir())
+				return baseDir
+			},
+			failedChecks: []string{"Git binary available"},
+			detail:       "executable file not found",
+		},
+		{
+			name: "invalid filter pattern",
+			setup: func(t *testing.T) string {
+				config.Properties.Filter.IncludePatterns = []string{"project-("}
+				return newCheckBaseDir(t)
+			},
+			failedChecks: []string{"Filter patterns compile"},
+			detail:       "project-(",
+		},
+		{
+			name: "backup directory not writable",
+			setup: func(t *testing.T) string {
+				baseDir := newCheckBaseDir(t)
+				file := filepath.Join(t.TempDir(), "file")
+				if err := os.WriteFile(file, nil, config.PermissionFile); err != nil {
+					t.Fatalf("could not write file: %v", err)
+				}
+				config.Properties.Backup.Enabled = true
+				config.Properties.Backup.Directory = filepath.Join(file, "backups")
+				return baseDir
+			},
+			failedChecks: []string{"Backup directory writable"},
+			detail:       "not a directory",
+		},
+		{
+			name: "no repositories",
+			setup: func(t *testing.T) string {
+				return t.TempDir()
+			},
+			failedChecks: []string{"Repositories discoverable"},
+			detail:       "no git repository found",
+		},
+		{
+			name: "HTTPS remote without credential helper",
+			setup: func(t *testing.T) string {
+				baseDir := newCheckBaseDir(t)
+				gittest.Run(t, filepath.Join(baseDir, "project"), "remote", "set-url", "origin", "https://example.com/project.git")
+				return baseDir
+			},
+			failedChecks: []string{"Credential helper for HTTPS remotes"},
+			detail:       "no credential helper for project",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetProperties(t)
+			// A credential helper of the user configuration would hide the missing one
+			t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+			t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+			config.Properties.Git.BaseDir = tt.setup(t)
+
+			var failed []string
+			var details []string
+			for _, result := range runChecks() {
+				if !result.Passed {
+					failed = append(failed, result.Name)
+					details = append(details, result.Detail)
+				}
+			}
+
+			if !slices.Equal(failed, tt.failedChecks) {
+				t.Fatalf("expected failed checks %v, got %v (details %v)", tt.failedChecks, failed, details)
+			}
+			if tt.detail != "" && !strings.Contains(details[0], tt.detail) {
+				t.Errorf("expected the detail of %q to contain %q, got %q", failed[0], tt.detail, details[0])
+			}
+		})
+	}
+}
+
+// newCheckBaseDir returns a base directory with one clone passing all checks
+func newCheckBaseDir(t *testing.T) string {
+	t.Helper()
+
+	remote, _ := gittest.NewRemote(t)
+	baseDir := t.TempDir()
+	gittest.Clone(t, remote, baseDir, "project")
+	return baseDir
+}
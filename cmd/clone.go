@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aeciopires/updateGit/internal/common"
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/aeciopires/updateGit/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// cloneCmd clones a repository inside the base directory
+	cloneCmd = &cobra.Command{
+		Use:   "clone <repository-url> [directory]",
+		Short: "Clone a git repository into the base directory",
+		Long: `Clone a git repository into the base directory, so it is updated by the next pull.
+
+If the directory is not informed, the name of the repository is used.
+
+Shallow clones (--git-clone-depth greater than 0) download only the last commits.
+They do not work well with the 'stash' backup strategy, because git stash
+depends on the history of the repository.
+
+Use --git-clone-single-branch to download only one branch: the branch informed
+by --git-branch or, if it is not informed, the default branch of the remote.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoURL := args[0]
+
+			directory := repoNameFromURL(repoURL)
+			if len(args) == 2 {
+				directory = args[1]
+			}
+
+			return runClone(repoURL, directory)
+		},
+	}
+)
+
+// init initializes the clone command and its flags
+func init() {
+	rootCmd.AddCommand(cloneCmd)
+
+	cloneCmd.Flags().IntVar(&config.Properties.Git.CloneDepth, "git-clone-depth", config.Properties.Git.CloneDepth, "Create a shallow clone with the given number of commits (0 means full history)")
+	cloneCmd.Flags().BoolVar(&config.Properties.Git.CloneSingleBranch, "git-clone-single-branch", config.Properties.Git.CloneSingleBranch, "Clone only one branch (the one of --git-branch or the default branch)")
+	cloneCmd.Flags().StringVar(&config.Properties.Git.CloneBranch, "git-branch", config.Properties.Git.CloneBranch, "Branch to checkout after the clone instead of the default branch")
+}
+
+// runClone clones the repository into the base directory
+func runClone(repoURL, directory string) error {
+	baseDir := config.Properties.Git.BaseDir
+	if baseDir == "" {
+		baseDir = "./git_repos"
+	}
+
+	if err := os.MkdirAll(baseDir, config.PermissionDir); err != nil {
+		return fmt.Errorf("failed to create base directory '%s': %w", baseDir, err)
+	}
+
+	if config.Properties.Git.CloneDepth > 0 && config.Properties.Backup.Enabled && config.Properties.Backup.Strategy == "stash" {
+		common.Logger("warning", "Shallow clone (depth=%d) with 'stash' backup strategy is configured. Stash backups may not work well with shallow history.", config.Properties.Git.CloneDepth)
+	}
+
+	destination := filepath.Join(baseDir, directory)
+	if common.DirExists(destination) {
+		return fmt.Errorf("destination directory already exists: %s", destination)
+	}
+
+	return git.CloneRepository(repoURL, destination, git.CloneOptions{
+		Depth:        config.Properties.Git.CloneDepth,
+		SingleBranch: config.Properties.Git.CloneSingleBranch,
+		Branch:       config.Properties.Git.CloneBranch,
+	})
+}
+
+// repoNameFromURL returns the repository name of a clone URL
+// e.g. https://github.com/aeciopires/updateGit.git => updateGit
+func repoNameFromURL(repoURL string) string {
+	name := strings.TrimSuffix(strings.TrimRight(repoURL, "/"), ".git")
+	if index := strings.LastIndexAny(name, "/:"); index >= 0 {
+		name = name[index+1:]
+	}
+	return name
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/aeciopires/updateGit/internal/common"
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// completionCmd generates the shell completion scripts
+	completionCmd = &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Generate the autocompletion script for the specified shell",
+		Long: `Generate the autocompletion script of updateGit for the specified shell.
+
+Bash (requires the bash-completion package):
+
+  # Current shell session
+  source <(updateGit completion bash)
+
+  # All sessions, on Linux
+  updateGit completion bash > /etc/bash_completion.d/updateGit
+
+  # All sessions, on macOS
+  updateGit completion bash > $(brew --prefix)/etc/bash_completion.d/updateGit
+
+Zsh:
+
+  # Enable completion in the environment, if it is not enabled yet
+  echo "autoload -U compinit; compinit" >> ~/.zshrc
+
+  # All sessions, on Linux
+  updateGit completion zsh > "${fpath[1]}/_updateGit"
+
+  # All sessions, on macOS
+  updateGit completion zsh > $(brew --prefix)/share/zsh/site-functions/_updateGit
+
+Fish:
+
+  # Current shell session
+  updateGit completion fish | source
+
+  # All sessions
+  updateGit completion fish > ~/.config/fish/completions/updateGit.fish
+
+PowerShell:
+
+  # Current shell session
+  updateGit completion powershell | Out-String | Invoke-Expression
+
+  # All sessions: add the output of the command above to your PowerShell profile
+
+Start a new shell for the setup to take effect.`,
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+	}
+
+	completionBashCmd = &cobra.Command{
+		Use:                   "bash",
+		Short:                 "Generate the autocompletion script for bash",
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return rootCmd.GenBashCompletionV2(os.Stdout, true)
+		},
+	}
+
+	completionZshCmd = &cobra.Command{
+		Use:                   "zsh",
+		Short:                 "Generate the autocompletion script for zsh",
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return rootCmd.GenZshCompletion(os.Stdout)
+		},
+	}
+
+	completionFishCmd = &cobra.Command{
+		Use:                   "fish",
+		Short:                 "Generate the autocompletion script for fish",
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return rootCmd.GenFishCompletion(os.Stdout, true)
+		},
+	}
+
+	completionPowerShellCmd = &cobra.Command{
+		Use:                   "powershell",
+		Short:                 "Generate the autocompletion script for powershell",
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+		},
+	}
+)
+
+// init initializes the completion command and replaces the default one created by cobra
+func init() {
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(completionCmd)
+
+	completionCmd.AddCommand(completionBashCmd, completionZshCmd, completionFishCmd, completionPowerShellCmd)
+}
+
+// registerEnumFlagCompletions completes the values of the global flags with a fixed list of values
+// instead of file names. It is called after the flags are defined in cmd/root.go
+func registerEnumFlagCompletions() {
+	enumFlags := map[string][]string{
+		"backup-strategy":   oneOfValues(config.BackupConfig{}, "Strategy"),
+		"git-pull-strategy": oneOfValues(config.GitConfig{}, "PullStrategy"),
+		"output":            oneOfValues(config.OutputConfig{}, "Format"),
+		"log-format":        oneOfValues(config.OutputConfig{}, "LogFormat"),
+	}
+	for flagName, values := range enumFlags {
+		if err := rootCmd.RegisterFlagCompletionFunc(flagName, cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp)); err != nil {
+			common.Logger("warning", "Could not register the completion of --%s: %v", flagName, err)
+		}
+	}
+}
+
+// oneOfValues returns the values accepted by the oneof rule of the validate tag of a config struct field,
+// so the completions follow the validation
+func oneOfValues(section any, fieldName string) []string {
+	field, found := reflect.TypeOf(section).FieldByName(fieldName)
+	if !found {
+		return nil
+	}
+
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if values, found := strings.CutPrefix(rule, "oneof="); found {
+			return strings.Fields(values)
+		}
+	}
+	return nil
+}
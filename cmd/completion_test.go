@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestEnumFlagCompletions(t *testing.T) {
+	tests := map[string][]string{
+		"backup-strategy":   {"copy", "stash", "auto", "bundle"},
+		"git-pull-strategy": {"merge", "rebase", "ff-only"},
+		"output":            {"text", "json", "yaml", "csv"},
+		"log-format":        {"console", "json"},
+	}
+
+	for flagName, expected := range tests {
+		t.Run(flagName, func(t *testing.T) {
+			completionFunc, found := rootCmd.GetFlagCompletionFunc(flagName)
+			if !found {
+				t.Fatalf("no completion registered for --%s", flagName)
+			}
+
+			values, directive := completionFunc(rootCmd, nil, "")
+			if !reflect.DeepEqual(values, expected) {
+				t.Errorf("expected %v, got %v", expected, values)
+			}
+			if directive != cobra.ShellCompDirectiveNoFileComp {
+				t.Errorf("expected file completion to be disabled, got directive %d", directive)
+			}
+		})
+	}
+}
+
+func TestOneOfValuesUnknownField(t *testing.T) {
+	if values := oneOfValues(struct{ Name string }{}, "Missing"); values != nil {
+		t.Errorf("expected no values, got %v", values)
+	}
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// configCmd groups the commands about the configuration
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Validate and show the configuration",
+		Long: `Validate and show the configuration resolved from the config file,
+the CLI_* environment variables, the flags and the default values.`,
+	}
+
+	// configValidateCmd reports whether the resolved configuration is valid
+	configValidateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration is valid",
+		Long: `Check the configuration is valid, with the same rules applied before a pull.
+The exit code is 0 if it is valid and 1 otherwise.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Annotations:  map[string]string{skipConfigLoadError: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			result := checkConfigFile()
+			if !result.Passed {
+				return errors.New(result.Detail)
+			}
+			fmt.Printf("Configuration valid: %s\n", result.Detail)
+			return nil
+		},
+	}
+
+	// configDumpCmd prints the resolved configuration
+	configDumpCmd = &cobra.Command{
+		Use:   "dump",
+		Short: "Print the resolved configuration",
+		Long: `Print the effective value of each setting, after merging the config file,
+the CLI_* environment variables, the flags and the default values.
+
+The configuration is printed as yaml, with the keys of the config file, or as json with --output json.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Annotations:  map[string]string{skipConfigLoadError: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if configLoadError != nil {
+				return configLoadError
+			}
+			return dumpConfig(os.Stdout, config.Properties, config.Properties.Output.Format)
+		},
+	}
+)
+
+// init initializes the config command and its subcommands
+func init() {
+	configCmd.AddCommand(configValidateCmd, configDumpCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// dumpConfig writes cfg as json when format is "json", and as yaml for "text" and "yaml"
+func dumpConfig(w io.Writer, cfg config.Config, format string) error {
+	node, err := configNode(reflect.ValueOf(cfg), "", nil)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		var settings map[string]any
+		if err := node.Decode(&settings); err != nil {
+			return fmt.Errorf("could not convert the configuration: %w", err)
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(settings)
+	case "csv":
+		return fmt.Errorf("output format %s is not supported by config dump, use yaml or json", format)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
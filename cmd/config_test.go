@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aeciopires/updateGit/internal/config"
+)
+
+func TestDumpConfig(t *testing.T) {
+	resetProperties(t)
+	config.Properties.Git.BaseDir = "/srv/git"
+	config.Properties.Filter.SkipRepos = []string{"legacy"}
+
+	var yamlOutput bytes.Buffer
+	if err := dumpConfig(&yamlOutput, config.Properties, "text"); err != nil {
+		t.Fatalf("dumpConfig failed: %v", err)
+	}
+	for _, text := range []string{"git:\n  base_dir: /srv/git\n", "skip_repos:\n    - legacy\n", "hooks:\n"} {
+		if !strings.Contains(yamlOutput.String(), text) {
+			t.Errorf("expected %q in yaml output:\n%s", text, yamlOutput.String())
+		}
+	}
+
+	var jsonOutput bytes.Buffer
+	if err := dumpConfig(&jsonOutput, config.Properties, "json"); err != nil {
+		t.Fatalf("dumpConfig failed: %v", err)
+	}
+	var settings struct {
+		Git    map[string]any `json:"git"`
+		Backup map[string]any `json:"backup"`
+	}
+	if err := json.Unmarshal(jsonOutput.Bytes(), &settings); err != nil {
+		t.Fatalf("invalid json output: %v\n%s", err, jsonOutput.String())
+	}
+	if settings.Git["base_dir"] != "/srv/git" || settings.Backup["strategy"] != config.Properties.Backup.Strategy {
+		t.Errorf("unexpected json output:\n%s", jsonOutput.String())
+	}
+
+	if err := dumpConfig(&bytes.Buffer{}, config.Properties, "csv"); err == nil {
+		t.Error("expected an error for the csv format")
+	}
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aeciopires/updateGit/internal/common"
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+var (
+	// gendocOutputDir is the directory where the documentation files are created
+	gendocOutputDir string
+
+	// gendocCmd generates the man pages of all commands. It is used by the release automation.
+	gendocCmd = &cobra.Command{
+		Use:    "gendoc",
+		Short:  "Generate the man pages of all commands",
+		Long:   "Generate the man pages of all commands. Use the subcommands to generate markdown or reStructuredText files.",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return generateDocs("man", func(dir string) error {
+				return doc.GenManTree(rootCmd, &doc.GenManHeader{Title: "UPDATEGIT", Section: "1"}, dir)
+			})
+		},
+	}
+
+	gendocMarkdownCmd = &cobra.Command{
+		Use:   "markdown",
+		Short: "Generate the documentation of all commands in markdown",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return generateDocs("markdown", func(dir string) error {
+				return doc.GenMarkdownTree(rootCmd, dir)
+			})
+		},
+	}
+
+	gendocRSTCmd = &cobra.Command{
+		Use:   "rst",
+		Short: "Generate the documentation of all commands in reStructuredText",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return generateDocs("rst", func(dir string) error {
+				return doc.GenReSTTree(rootCmd, dir)
+			})
+		},
+	}
+)
+
+// init initializes the gendoc command and its flags
+func init() {
+	rootCmd.AddCommand(gendocCmd)
+	gendocCmd.AddCommand(gendocMarkdownCmd, gendocRSTCmd)
+
+	gendocCmd.PersistentFlags().StringVar(&gendocOutputDir, "dir", "./docs", "Directory where the documentation files are created")
+}
+
+// generateDocs creates the output directory and calls generate with it
+func generateDocs(format string, generate func(dir string) error) error {
+	if err := os.MkdirAll(gendocOutputDir, config.PermissionDir); err != nil {
+		return fmt.Errorf("failed to create documentation directory '%s': %w", gendocOutputDir, err)
+	}
+
+	// The generation date changes every run, the files are only regenerated when the commands change
+	rootCmd.DisableAutoGenTag = true
+
+	if err := generate(gendocOutputDir); err != nil {
+		return fmt.Errorf("failed to generate %s documentation: %w", format, err)
+	}
+
+	common.Logger("info", "Documentation generated. format=%s dir=%s", format, gendocOutputDir)
+	return nil
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+
+	"github.com/aeciopires/updateGit/internal/common"
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// configKeyComments are the comments written above each section and key of the generated config file.
+// A new field of config.Config must have a comment here, checked by TestConfigKeyComments.
+var configKeyComments = map[string]string{
+	"git":                          "Git settings",
+	"git.base_dir":                 "Base directory for git repositories",
+	"git.parallel_enabled":         "Enable parallel processing of git repositories.\nDisable it if git asks for login/password, because the prompts of parallel pulls are mixed",
+	"git.max_concurrent":           "Maximum number of concurrent git repository updates",
+	"git.repo_timeout":             "Seconds the pull of each repository can take before it is killed (0 uses the default of 60 seconds).\nA login/password prompt of git is killed too, configure a credential helper for HTTPS remotes",
+	"git.total_timeout":            "Seconds the pull of all repositories can take before the running pulls are killed (0 means no limit)",
+	"git.scan_depth":               "Levels of directories scanned for repositories below base_dir, from 0 to 20.\n0 means unlimited depth: use with caution, mainly in high-level directories like / or $HOME",
+	"git.clone_depth":              "Number of commits of shallow clones created by the clone command (0 means full history)",
+	"git.clone_branch":             "Branch checked out by the clone command (empty means the default branch)",
+	"git.clone_single_branch":      "Clone only one branch (clone_branch or the default branch)",
+	"git.commit_message_template":  "Go template for the message of merge commits created by pull. Empty keeps the git message",
+	"git.extra_pull_args":          "Extra arguments passed to git pull. Strategy arguments like --rebase and --no-rebase are not allowed",
+	"git.pull_strategy":            "Strategy of git pull: \"merge\", \"rebase\" or \"ff-only\". Empty keeps the git configuration (pull.rebase, pull.ff)",
+	"git.max_pack_size":            "Maximum memory in MiB to handle packs (pack.windowMemory). 0 keeps the git configuration",
+	"git.http_low_speed_limit":     "Abort transfers slower than http_low_speed_limit bytes per second for http_low_speed_time seconds.\n0 keeps the git configuration",
+	"git.http_low_speed_time":      "Seconds below http_low_speed_limit before a transfer is aborted",
+	"git.credential_helper":        "Credential helper (credential.helper) of HTTPS remotes, e.g. \"cache\" (in memory) or \"store\" (plaintext file).\nEmpty uses the helpers of the git configuration",
+	"git.safe_directory":           "Absolute path of a repository owned by another user that git can use (safe.directory).\n\"*\" trusts all repositories",
+	"git.sign_commits":             "GPG-sign the merge commits created by pull",
+	"git.gpg_key_id":               "Key used by sign_commits. Empty uses the key of the git configuration",
+	"git.fetch_depth":              "Depth of the fetch executed before the pull (0 means not limited). The pull is not affected",
+	"git.user_name":                "Identity of the merge commits created by pull, useful in CI without git configuration.\nEmpty values use user.name and user.email of the git configuration",
+	"git.user_email":               "Email of the identity of the merge commits created by pull",
+	"git.tag_after_pull":           "Annotated tag created at HEAD after a successful pull (empty disables it).\nName and message are Go templates, e.g. \"sync-{{.CurrentBranch}}\"",
+	"git.tag_message":              "Message of the tag created by tag_after_pull",
+	"git.push_tags":                "Push the tag to origin",
+	"git.force_tag":                "Replace the tag if it already exists",
+	"git.worktree_prune":           "Run \"git worktree prune\" after each pull to remove references to deleted worktrees",
+	"git.include_submodule_repos":  "Update the repositories that are submodules of the base directory repository (listed in its .gitmodules)",
+	"git.pull_only_if_behind":      "Fetch first and skip the pull of repositories whose upstream has no new commits",
+	"git.auto_stash":               "Stash the uncommitted changes before the pull and restore them after it",
+	"git.post_pull_diff_stat":      "Show the diff stat (git diff --stat) of the commits added by each pull",
+	"git.fetch_all_remotes":        "Fetch every remote of the repository (e.g. upstream, fork, mirror) before the pull",
+	"git.fetch_prune":              "Remove the remote-tracking branches deleted from the remotes in the fetch of fetch_all_remotes and fetch_before_pull",
+	"git.fetch_before_pull":        "Run git fetch --all before the pull, e.g. to see the ahead/behind counts of all remote branches",
+	"git.update_submodules":        "Run git submodule update --init --recursive after each successful pull",
+	"git.submodule_jobs":           "Submodules fetched in parallel by update_submodules (0 keeps the git default)",
+	"git.submodule_depth":          "Commits fetched for each submodule by update_submodules (0 means full history)",
+	"backup":                       "Backup settings",
+	"backup.enabled":               "Enable backup before updates",
+	"backup.directory":             "Backup directory (relative or absolute path)",
+	"backup.strategy":              "Backup strategy: \"copy\", \"stash\", \"auto\" or \"bundle\"",
+	"backup.exclude_git_dir":       "Skip the .git directory in copy backups",
+	"backup.stash_drop_on_restore": "Drop the stash entry after a stash backup is restored",
+	"backup.retention_days":        "Remove the backups older than the number of days after a successful pull. 0 keeps all backups",
+	"filter":                       "Repository filtering",
+	"filter.skip_repos":            "Specific repositories to skip (exact names)",
+	"filter.include_patterns":      "Regular expressions of repository names to process. Empty list processes all repositories",
+	"filter.exclude_patterns":      "Regular expressions of repository names to skip. It has priority over include_patterns",
+	"filter.list_skipped":          "Print the repositories excluded by the filter and the reason after the pull",
+	"output":                       "Output settings",
+	"output.format":                "Output format of the status and list commands: \"text\" (a table), \"json\", \"yaml\" or \"csv\"",
+	"output.log_format":            "Log format: \"console\" or \"json\"",
+	"output.log_file":              "Write log messages to this file instead of stdout (empty means stdout)",
+	"output.quiet":                 "Show only warning and error messages",
+	"output.color":                 "Enable colored log output",
+	"output.log_timestamp_format":  "Timestamp format of log messages: a Go time layout or \"unix\" for Unix epoch seconds",
+	"output.log_timezone":          "Timezone of log timestamps: \"local\", \"utc\" or an IANA timezone name (e.g. \"America/New_York\")",
+	"output.template":              "Go template file rendered with the summary of the pull",
+	"hooks":                        "Scripts executed around the pull of each repository. They must be executable files.\nThe settings are validated, but the scripts are not executed yet",
+	"hooks.pre_pull":               "Script executed before the pull",
+	"hooks.post_pull":              "Script executed after the pull",
+	"hooks.strict_mode":            "Fail the update of the repository when a hook fails",
+}
+
+var (
+	// initForce overwrites an existing config file
+	initForce bool
+
+	// initCmd writes a config file with the default values
+	initCmd = &cobra.Command{
+		Use:   "init",
+		Short: "Create a config file with the default values",
+		Long: `Create a commented config file with all the settings and their default values.
+
+The file is written to the path of --config-file (.updateGit.yaml in the current directory by default).
+An existing file is only replaced with --force.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Annotations:  map[string]string{skipConfigLoadError: "true"},
+		// The config file informed by --config-file is created, so it does not need to exist
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := config.Properties.DefaultConfigFile
+			if err := writeDefaultConfigFile(path, initForce); err != nil {
+				return err
+			}
+			fmt.Printf("Config file created: %s\n", path)
+			return nil
+		},
+	}
+)
+
+// init initializes the init command and its flags
+func init() {
+	initCmd.Flags().BoolVar(&initForce, "force", false, "Overwrite the config file if it already exists")
+
+	rootCmd.AddCommand(initCmd)
+}
+
+// writeDefaultConfigFile writes the default configuration to path. It fails if path exists, unless force is true
+func writeDefaultConfigFile(path string, force bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("config file already exists: %s. Use --force to overwrite it", path)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("could not check config file %s: %w", path, err)
+	}
+
+	content, err := defaultConfigYAML()
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, content, config.PermissionFile); err != nil {
+		return fmt.Errorf("could not write config file %s: %w", path, err)
+	}
+	common.Logger("debug", "Default config file written. path=%s", path)
+	return nil
+}
+
+// defaultConfigYAML returns the sections of config.Config with the values of config.SetDefaultConfig
+// and the comments of configKeyComments. config.Properties is not changed.
+func defaultConfigYAML() ([]byte, error) {
+	current := config.Properties
+	defer func() { config.Properties = current }()
+	config.Properties = config.Config{}
+	config.SetDefaultConfig()
+	defaults := config.Properties
+
+	var content bytes.Buffer
+	value := reflect.ValueOf(defaults)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		key := field.Tag.Get("mapstructure")
+		// Only the sections are written, settings like the path of the config file are not read from it
+		if !field.IsExported() || key == "" || field.Type.Kind() != reflect.Struct {
+			continue
+		}
+
+		section, err := configNode(value.Field(i), key, configKeyComments)
+		if err != nil {
+			return nil, err
+		}
+		document := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: key, HeadComment: configKeyComments[key]},
+			section,
+		}}
+
+		if content.Len() > 0 {
+			content.WriteString("\n")
+		}
+		encoder := yaml.NewEncoder(&content)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(document); err != nil {
+			return nil, fmt.Errorf("could not encode the %s section: %w", key, err)
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, fmt.Errorf("could not encode the %s section: %w", key, err)
+		}
+	}
+	return content.Bytes(), nil
+}
+
+// configNode returns a mapping node with the fields of a config struct, using the mapstructure keys of the
+// config file. Each key is commented with its entry in comments, which is keyed by the full path (e.g. "git.base_dir")
+func configNode(section reflect.Value, prefix string, comments map[string]string) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i < section.NumField(); i++ {
+		field := section.Type().Field(i)
+		key := field.Tag.Get("mapstructure")
+		if !field.IsExported() || key == "" {
+			continue
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		value := &yaml.Node{}
+		if section.Field(i).Kind() == reflect.Struct {
+			var err error
+			if value, err = configNode(section.Field(i), key, comments); err != nil {
+				return nil, err
+			}
+		} else if err := value.Encode(section.Field(i).Interface()); err != nil {
+			return nil, fmt.Errorf("could not encode %s: %w", key, err)
+		}
+		// Empty lists are written as [] instead of one item per line
+		if value.Kind == yaml.SequenceNode && len(value.Content) == 0 {
+			value.Style = yaml.FlowStyle
+		}
+
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: field.Tag.Get("mapstructure"), HeadComment: comments[key]},
+			value,
+		)
+	}
+	return node, nil
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/spf13/viper"
+)
+
+func TestConfigKeyComments(t *testing.T) {
+	keys := make(map[string]bool)
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	config.SetViperDefaults()
+	for _, key := range viper.AllKeys() {
+		if key == "cli_config_file" {
+			continue
+		}
+		section, _, _ := strings.Cut(key, ".")
+		keys[key], keys[section] = true, true
+	}
+
+	for key := range keys {
+		if configKeyComments[key] == "" {
+			t.Errorf("missing comment of %s in configKeyComments", key)
+		}
+	}
+	for key := range configKeyComments {
+		if !keys[key] {
+			t.Errorf("comment of unknown key %s in configKeyComments", key)
+		}
+	}
+}
+
+func TestWriteDefaultConfigFile(t *testing.T) {
+	configFile := setupConfigFile(t, "")
+	if err := os.Remove(configFile); err != nil {
+		t.Fatalf("could not remove config file: %v", err)
+	}
+	config.Properties.Git.MaxConcurrent = 42
+
+	if err := writeDefaultConfigFile(configFile, false); err != nil {
+		t.Fatalf("writeDefaultConfigFile failed: %v", err)
+	}
+	if config.Properties.Git.MaxConcurrent != 42 {
+		t.Errorf("config.Properties was changed: max_concurrent=%d", config.Properties.Git.MaxConcurrent)
+	}
+
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("could not read config file: %v", err)
+	}
+	if !strings.Contains(string(content), "# Base directory for git repositories\n  base_dir: ./git_repos") {
+		t.Errorf("expected commented keys in config file:\n%s", content)
+	}
+
+	if err := writeDefaultConfigFile(configFile, false); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("expected an error for an existing file, got %v", err)
+	}
+	if err := writeDefaultConfigFile(configFile, true); err != nil {
+		t.Errorf("expected the file to be overwritten with force, got %v", err)
+	}
+
+	// The generated file loads into the default configuration
+	config.Properties = config.Config{}
+	config.SetDefaultConfig()
+	defaults := config.Properties
+	config.Properties.DefaultConfigFile = configFile
+	if err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	defaults.DefaultConfigFile = configFile
+	if !reflect.DeepEqual(config.Properties, defaults) {
+		t.Errorf("loaded config differs from the defaults:\n got: %+v\nwant: %+v", config.Properties, defaults)
+	}
+}
+
+func TestWriteDefaultConfigFileMissingDirectory(t *testing.T) {
+	resetProperties(t)
+
+	configFile := filepath.Join(t.TempDir(), "missing", "updateGit.yaml")
+	if err := writeDefaultConfigFile(configFile, false); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/aeciopires/updateGit/internal/git"
+	"github.com/spf13/cobra"
+)
+
+// repoListEntry is a repository reported by the list command
+type repoListEntry struct {
+	Repository string `json:"repository" yaml:"repository"`
+	Path       string `json:"path" yaml:"path"`
+	Branch     string `json:"branch" yaml:"branch"`
+	// RemoteURL is the URL of the origin remote, empty when the repository has no origin
+	RemoteURL string `json:"remote_url" yaml:"remote_url"`
+	Dirty     bool   `json:"dirty" yaml:"dirty"`
+	// HasUpstream is false when the branch does not track a remote branch, so Ahead and Behind are not known
+	HasUpstream bool `json:"has_upstream" yaml:"has_upstream"`
+	Ahead       int  `json:"ahead" yaml:"ahead"`
+	Behind      int  `json:"behind" yaml:"behind"`
+	// Tags are the tags pointing to HEAD
+	Tags []string `json:"tags" yaml:"tags"`
+}
+
+var (
+	// listCmd lists the repositories that the pull command would update
+	listCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the git repositories found in the base directory",
+		Long: `List the repositories found in the base directory after the filters, that is,
+the repositories the pull command would update, with their branch, origin URL
+and the tags pointing to HEAD.
+
+Repositories with uncommitted changes are marked with "*". The commits ahead and behind
+the upstream are counted against the last fetch, as nothing is fetched or changed.
+Use --output to print the list as text, json, yaml or csv.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			baseDir := config.Properties.Git.BaseDir
+			if baseDir == "" {
+				baseDir = "./git_repos"
+			}
+
+			entries, err := listRepositories(baseDir)
+			if err != nil {
+				return err
+			}
+			return printRepoList(os.Stdout, entries, config.Properties.Output.Format)
+		},
+	}
+)
+
+// init initializes the list command
+func init() {
+	rootCmd.AddCommand(listCmd)
+}
+
+// listRepositories finds the repositories of baseDir accepted by the configured filter
+func listRepositories(baseDir string) ([]repoListEntry, error) {
+	if err := validateBaseDir(baseDir); err != nil {
+		return nil, err
+	}
+
+	repoFilter, err := initializeFilter()
+	if err != nil {
+		return nil, err
+	}
+
+	repositories, err := git.FindRepositoriesRecursive(baseDir, config.Properties.Git.MaxDepth, config.Properties.Git.IncludeSubmoduleRepos)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]repoListEntry, 0, len(repositories))
+	for _, repo := range repositories {
+		if !repoFilter.ShouldProcess(repo.Name) {
+			continue
+		}
+
+		entry := repoListEntry{
+			Repository: repo.Name,
+			Path:       repo.Path,
+			Branch:     repo.CurrentBranch,
+			Dirty:      git.HasUncommittedChanges(repo.Path),
+			Tags:       repo.Tags,
+		}
+		// A repository without origin is listed with an empty URL
+		entry.RemoteURL, _ = git.GetRemoteURL(repo.Path, "origin")
+		if ahead, behind, err := git.CountCommitsAheadBehind(repo.Path); err == nil {
+			entry.HasUpstream = true
+			entry.Ahead, entry.Behind = ahead, behind
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// printRepoList writes the repositories in the output format: a table for "text", or a json, yaml or csv document
+func printRepoList(w io.Writer, entries []repoListEntry, format string) error {
+	switch format {
+	case "json", "yaml":
+		return encodeDocument(w, entries, format)
+	case "csv":
+		records := [][]string{{"repository", "path", "branch", "remote_url", "dirty", "has_upstream", "ahead", "behind", "tags"}}
+		for _, entry := range entries {
+			records = append(records, []string{
+				entry.Repository,
+				entry.Path,
+				entry.Branch,
+				entry.RemoteURL,
+				strconv.FormatBool(entry.Dirty),
+				strconv.FormatBool(entry.HasUpstream),
+				strconv.Itoa(entry.Ahead),
+				strconv.Itoa(entry.Behind),
+				strings.Join(entry.Tags, " "),
+			})
+		}
+		return csv.NewWriter(w).WriteAll(records)
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No repositories found")
+		return nil
+	}
+
+	fmt.Fprintf(w, "%-30s %-20s %-6s %-6s %-20s %-50s %s\n", "REPOSITORY", "BRANCH", "AHEAD", "BEHIND", "TAGS", "REMOTE", "PATH")
+	for _, entry := range entries {
+		name := entry.Repository
+		if entry.Dirty {
+			name += "*"
+		}
+		ahead, behind := "-", "-"
+		if entry.HasUpstream {
+			ahead, behind = strconv.Itoa(entry.Ahead), strconv.Itoa(entry.Behind)
+		}
+		fmt.Fprintf(w, "%-30s %-20s %-6s %-6s %-20s %-50s %s\n", name, entry.Branch, ahead, behind, formatTags(entry.Tags), entry.RemoteURL, entry.Path)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/aeciopires/updateGit/internal/gittest"
+)
+
+func TestListRepositories(t *testing.T) {
+	resetProperties(t)
+
+	bareRepo, _ := gittest.NewRemote(t)
+	baseDir := t.TempDir()
+
+	cloneRepo := gittest.Clone(t, bareRepo, baseDir, "project")
+	gittest.CommitFile(t, cloneRepo, "local.txt", "local", "local commit")
+	gittest.Run(t, cloneRepo, "tag", "v1.1.0")
+	gittest.Clone(t, bareRepo, baseDir, "legacy")
+
+	config.Properties.Git.MaxDepth = 1
+	config.Properties.Filter.SkipRepos = []string{"legacy"}
+
+	entries, err := listRepositories(baseDir)
+	if err != nil {
+		t.Fatalf("listRepositories failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected only the repository accepted by the filter, got %+v", entries)
+	}
+
+	entry := entries[0]
+	if entry.Repository != "project" || entry.Branch != "main" || entry.RemoteURL != bareRepo {
+		t.Errorf("unexpected repository metadata: %+v", entry)
+	}
+	if !entry.HasUpstream || entry.Ahead != 1 || entry.Behind != 0 || entry.Dirty {
+		t.Errorf("expected a clean repository 1 commit ahead of its upstream, got %+v", entry)
+	}
+	if len(entry.Tags) != 1 || entry.Tags[0] != "v1.1.0" {
+		t.Errorf("expected the tag pointing to HEAD, got %v", entry.Tags)
+	}
+}
+
+func TestPrintRepoListCSV(t *testing.T) {
+	entries := []repoListEntry{
+		{Repository: "api", Path: "/git/api", Branch: "main", RemoteURL: "https://example.com/api.git", Dirty: true, HasUpstream: true, Behind: 3, Tags: []string{"v1.0.0", "stable"}},
+	}
+
+	var output bytes.Buffer
+	if err := printRepoList(&output, entries, "csv"); err != nil {
+		t.Fatalf("printRepoList failed: %v", err)
+	}
+
+	records, err := csv.NewReader(&output).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv output: %v\n%s", err, output.String())
+	}
+	if len(records) != 2 || records[0][0] != "repository" {
+		t.Fatalf("expected a header and one record, got %v", records)
+	}
+	expected := []string{"api", "/git/api", "main", "https://example.com/api.git", "true", "true", "0", "3", "v1.0.0 stable"}
+	for i, value := range expected {
+		if records[1][i] != value {
+			t.Errorf("unexpected value of column %s: got '%s', want '%s'", records[0][i], records[1][i], value)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/aeciopires/updateGit/internal/git"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// pruneCmd deletes the local branches whose upstream branch was deleted from the remote
+	pruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Delete the local branches whose upstream branch was deleted from the remote",
+		Long: `Delete the stale tracking branches of the repositories in the base directory, that is,
+the local branches whose upstream branch was deleted from the remote.
+
+The upstream is only known to be deleted after a fetch with --prune, e.g. a pull with
+--git-fetch-all-remotes and --git-fetch-prune. The branches are deleted with
+'git branch -d', so branches that are not fully merged and the current branch are kept.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			baseDir := config.Properties.Git.BaseDir
+			if baseDir == "" {
+				baseDir = "./git_repos"
+			}
+
+			return pruneStaleBranches(os.Stdout, baseDir)
+		},
+	}
+)
+
+// init initializes the prune command
+func init() {
+	rootCmd.AddCommand(pruneCmd)
+}
+
+// pruneStaleBranches deletes the stale tracking branches of the repositories of baseDir accepted by the filter
+// and writes one line for each branch to w. It fails if a branch could not be deleted.
+func pruneStaleBranches(w io.Writer, baseDir string) error {
+	if err := validateBaseDir(baseDir); err != nil {
+		return err
+	}
+
+	repoFilter, err := initializeFilter()
+	if err != nil {
+		return err
+	}
+
+	repositories, err := git.FindRepositoriesRecursive(baseDir, config.Properties.Git.MaxDepth, config.Properties.Git.IncludeSubmoduleRepos)
+	if err != nil {
+		return err
+	}
+
+	deleted, failed := 0, 0
+	for _, repo := range repositories {
+		if !repoFilter.ShouldProcess(repo.Name) {
+			continue
+		}
+
+		staleBranches, err := git.GetStaleTrackingBranches(repo.Path)
+		if err != nil {
+			fmt.Fprintf(w, "%s: could not list the stale branches: %v\n", repo.Name, err)
+			failed++
+			continue
+		}
+
+		for _, branch := range staleBranches {
+			if branch == repo.CurrentBranch {
+				fmt.Fprintf(w, "%s: kept %s, it is the current branch\n", repo.Name, branch)
+				continue
+			}
+			if err := git.DeleteBranch(repo.Path, branch); err != nil {
+				fmt.Fprintf(w, "%s: could not delete %s: %v\n", repo.Name, branch, err)
+				failed++
+				continue
+			}
+			fmt.Fprintf(w, "%s: deleted %s\n", repo.Name, branch)
+			deleted++
+		}
+	}
+
+	fmt.Fprintf(w, "Deleted %d stale branches\n", deleted)
+	if failed > 0 {
+		return fmt.Errorf("%d errors while deleting the stale branches", failed)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aeciopires/updateGit/internal/gittest"
+)
+
+func TestPruneStaleBranches(t *testing.T) {
+	resetProperties(t)
+
+	bareRepo, seedRepo := gittest.NewRemote(t)
+	baseDir := t.TempDir()
+	gittest.Run(t, seedRepo, "push", "origin", "HEAD:merged", "HEAD:unmerged")
+
+	cloneRepo := gittest.Clone(t, bareRepo, baseDir, "project")
+	for _, branch := range []string{"merged", "unmerged"} {
+		gittest.Run(t, cloneRepo, "branch", "--track", branch, "origin/"+branch)
+	}
+	gittest.Run(t, cloneRepo, "checkout", "unmerged")
+	gittest.CommitFile(t, cloneRepo, "local.txt", "local", "local commit")
+	gittest.Run(t, cloneRepo, "checkout", "main")
+
+	gittest.Run(t, seedRepo, "push", "origin", "--delete", "merged", "unmerged")
+	gittest.Run(t, cloneRepo, "fetch", "--prune")
+
+	var output bytes.Buffer
+	err := pruneStaleBranches(&output, baseDir)
+	if err == nil {
+		t.Fatalf("expected an error for the branch that is not fully merged, got output:\n%s", output.String())
+	}
+	for _, text := range []string{"project: deleted merged", "project: could not delete unmerged", "Deleted 1 stale branches"} {
+		if !strings.Contains(output.String(), text) {
+			t.Errorf("expected %q in output:\n%s", text, output.String())
+		}
+	}
+
+	branches := gittest.Run(t, cloneRepo, "branch", "--format=%(refname:short)")
+	if got := strings.Fields(branches); strings.Join(got, ",") != "main,unmerged" {
+		t.Errorf("expected only the unmerged stale branch to be kept, got %v", got)
+	}
+}
@@ -1,7 +1,16 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"text/template"
 	"time"
 
 	"github.com/aeciopires/updateGit/internal/backup"
@@ -13,31 +22,107 @@ import (
 )
 
 var (
+	// pullDryRun lists the repositories that would be updated instead of updating them
+	pullDryRun bool
+
+	// pullRebase is a shortcut of --git-pull-strategy=rebase
+	pullRebase bool
+
+	// pullWatch is the interval between the pulls of --watch mode. 0 runs the pull once
+	pullWatch time.Duration
+
+	// pullConfigFileWatch reloads the config file between the pulls of --watch mode when it changes
+	pullConfigFileWatch bool
+
 	// runUpdateCmd is the command to run the update process)
 	runUpdateCmd = &cobra.Command{
 		Use:   "pull",
 		Short: "Update git repositories",
-		Long:  "Update all git repositories in the specified base directory with optional parallel processing and backup.",
+		Long: `Update all git repositories in the specified base directory with optional parallel processing and backup.
+
+Use --dry-run to list the repositories that would be updated, after the filters,
+without running the backup and the pull.
+
+Use --watch to run the pull again after each interval until the process is
+interrupted. With --config-file-watch, the changes of the config file are
+applied from the next pull on, without restarting.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			baseDir := config.Properties.Git.BaseDir
+			if pullConfigFileWatch && pullWatch <= 0 {
+				return errors.New("--config-file-watch requires --watch")
+			}
 
-			if baseDir == "" {
-				baseDir = "./git_repos"
+			// cobra sets the context in Execute, but not when the command runs without it (e.g. in tests)
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
 			}
 
-			return runUpdate(baseDir)
+			if pullWatch > 0 {
+				ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+				defer stop()
+				return watchPull(ctx, pullWatch, pullConfigFileWatch, runPull)
+			}
+
+			err := runPull(ctx)
+			// Failed repositories are already reported, so the usage is not shown.
+			// Execute sets the exit code from the returned error
+			var updateErr *git.UpdateError
+			if errors.As(err, &updateErr) {
+				cmd.SilenceUsage = true
+			}
+			return err
 		},
 	}
 )
 
 // init initializes the update command and its flags
 func init() {
+	runUpdateCmd.Flags().BoolVarP(&pullDryRun, "dry-run", "n", false, "List the repositories that would be updated without running the backup and the pull")
+	runUpdateCmd.Flags().BoolVar(&pullRebase, "rebase", false, "Rebase the local commits on the upstream instead of merging (same as --git-pull-strategy=rebase)")
+	runUpdateCmd.Flags().DurationVar(&pullWatch, "watch", 0, "Run the pull again after each interval (e.g. '10m') until interrupted. 0 runs the pull once")
+	runUpdateCmd.Flags().BoolVar(&pullConfigFileWatch, "config-file-watch", false, "With --watch, reload the config file when it changes. The changes apply from the next pull")
+
 	// Add the update command to the root command
 	rootCmd.AddCommand(runUpdateCmd)
 }
 
+// runPull updates the repositories of the base directory and prints the reports of the pull flags.
+// It returns a *git.UpdateError if some repository failed
+func runPull(ctx context.Context) error {
+	baseDir := config.Properties.Git.BaseDir
+	if baseDir == "" {
+		baseDir = "./git_repos"
+	}
+
+	summary, err := runUpdate(ctx, baseDir)
+	var updateErr *git.UpdateError
+	if err != nil && !errors.As(err, &updateErr) {
+		return err
+	}
+
+	if pullDryRun {
+		printDryRun(os.Stdout, summary.Results)
+	}
+
+	if config.Properties.Filter.ListSkipped {
+		printSkippedRepos(os.Stdout, summary.Skipped)
+	}
+
+	if templateFile := config.Properties.Output.Template; templateFile != "" {
+		if err := renderSummaryTemplate(os.Stdout, templateFile, summary); err != nil {
+			return err
+		}
+	}
+
+	if updateErr != nil {
+		return updateErr
+	}
+	return nil
+}
+
 // runUpdate executes the main update logic with all enhanced features
-func runUpdate(baseDir string) error {
+// and returns the summary with the result of each repository. When ctx is done, the update is interrupted
+func runUpdate(ctx context.Context, baseDir string) (*git.UpdateSummary, error) {
 	common.Logger("info", "Starting enhanced git repositories update. baseDir=%s parallel=%t max_concurrent=%d backup_enabled=%t backup_dir=%s skip_repos=%s",
 		baseDir,
 		config.Properties.Git.Parallel,
@@ -47,8 +132,8 @@ func runUpdate(baseDir string) error {
 		config.Properties.Filter.SkipRepos,
 	)
 
-	if !common.DirExists(baseDir) {
-		common.Logger("fatal", "Directory validation failed: directory does not exist: %s", baseDir)
+	if err := validateBaseDir(baseDir); err != nil {
+		common.Logger("fatal", "Directory validation failed: %v", err)
 	}
 
 	// Get absolute path
@@ -59,50 +144,176 @@ func runUpdate(baseDir string) error {
 
 	common.Logger("debug", "Using absolute path: %s", absBaseDir)
 
+	if config.Properties.Git.MaxDepth == 0 && isHighLevelDir(absBaseDir) {
+		common.Logger("warning", "Unlimited scan depth (--git-scan-depth=0) in a high-level directory can scan the whole file system. Use a positive depth. baseDir=%s", absBaseDir)
+	}
+
 	// Initialize repository filter
 	repoFilter, err := initializeFilter()
 	if err != nil {
 		common.Logger("fatal", "Failed to initialize filter: %w", err)
 	}
 
-	// Initialize backup manager
-	backupManager, err := initializeBackupManager()
-	if err != nil {
-		common.Logger("fatal", "Failed to initialize backup manager: %w", err)
-	}
-
-	// Create update configuration
-	updateConfig := git.UpdateConfig{
-		BaseDir: absBaseDir,
-		Parallel: git.ParallelUpdateConfig{
-			Enabled:       config.Properties.Git.Parallel,
-			MaxConcurrent: config.Properties.Git.MaxConcurrent,
-			Timeout:       time.Duration(config.Timeout) * time.Second,
-		},
-		BackupEnabled: config.Properties.Backup.Enabled,
-		BackupManager: backupManager,
-		Filter:        repoFilter,
+	// Initialize backup manager. It creates the backup directory, so it is skipped in a dry run
+	var backupManager *backup.BackupManager
+	if !pullDryRun {
+		backupManager, err = initializeBackupManager()
+		if err != nil {
+			common.Logger("fatal", "Failed to initialize backup manager: %w", err)
+		}
 	}
 
-	// Set default timeout if not configured
-	if updateConfig.Parallel.Timeout == 0 {
-		updateConfig.Parallel.Timeout = 5 * time.Minute
-	}
+	updateConfig := newPullUpdateConfig(absBaseDir, repoFilter, backupManager)
 
 	var filterStats any
 	if repoFilter != nil {
 		filterStats = repoFilter.GetStats()
 	}
-	common.Logger("info", "Update configuration prepared. parallel=%t max_concurrent=%d timeout=%v backup=%t filter_stats=%v",
+	common.Logger("info", "Update configuration prepared. parallel=%t max_concurrent=%d timeout=%v total_timeout=%v backup=%t filter_stats=%v",
 		updateConfig.Parallel.Enabled,
 		updateConfig.Parallel.MaxConcurrent,
 		updateConfig.Parallel.Timeout,
+		updateConfig.TotalTimeout,
 		updateConfig.BackupEnabled,
 		filterStats,
 	)
 
 	// Execute repository updates with backup/filter support
-	return git.UpdateRepositoriesWithConfig(updateConfig)
+	summary, err := git.UpdateRepositoriesWithSummaryContext(ctx, updateConfig)
+	if err == nil && backupManager != nil && config.Properties.Backup.RetentionDays > 0 {
+		// A failed cleanup does not change the result of the pull
+		if cleanupErr := backupManager.CleanupOldBackups(config.Properties.Backup.RetentionDays); cleanupErr != nil {
+			common.Logger("warning", "Failed to remove old backups: %v", cleanupErr)
+		}
+	}
+	return summary, err
+}
+
+// newPullUpdateConfig creates the configuration of the update from the properties and the pull flags.
+// A repository timeout not configured uses the default of the git package
+func newPullUpdateConfig(absBaseDir string, repoFilter *filter.Filter, backupManager *backup.BackupManager) git.UpdateConfig {
+	pullStrategy := git.PullStrategy(config.Properties.Git.PullStrategy)
+	if pullRebase {
+		pullStrategy = git.PullStrategyRebase
+	}
+
+	// A nil pointer stored in an interface is not a nil interface, so the nil pointers become untyped nils
+	var backupProvider git.BackupProvider
+	if backupManager != nil {
+		backupProvider = backupManager
+	}
+	var repositoryFilter git.RepositoryFilter
+	if repoFilter != nil {
+		repositoryFilter = repoFilter
+	}
+
+	opts := []git.UpdateOption{
+		git.WithBaseDir(absBaseDir),
+		git.WithMaxDepth(config.Properties.Git.MaxDepth),
+		git.WithIncludeSubmoduleRepos(config.Properties.Git.IncludeSubmoduleRepos),
+		git.WithParallel(config.Properties.Git.Parallel, config.Properties.Git.MaxConcurrent),
+		git.WithBackup(config.Properties.Backup.Enabled, backupProvider),
+		git.WithFilter(repositoryFilter),
+		git.WithCommitMessageTemplate(config.Properties.Git.CommitMessageTemplate),
+		git.WithPullOnlyIfBehind(config.Properties.Git.PullOnlyIfBehind),
+		git.WithAutoStash(config.Properties.Git.AutoStash),
+		git.WithPostPullDiffStat(config.Properties.Git.PostPullDiffStat),
+		git.WithFetchAllRemotes(config.Properties.Git.FetchAllRemotes),
+		git.WithFetchPrune(config.Properties.Git.FetchPrune),
+		git.WithFetchBeforePull(config.Properties.Git.FetchBeforePull),
+		git.WithPruneWorktrees(config.Properties.Git.WorktreePrune),
+		git.WithDryRun(pullDryRun),
+		git.WithPullStrategy(pullStrategy),
+		git.WithExtraPullArgs(config.Properties.Git.ExtraPullArgs...),
+		git.WithCommitIdentity(config.Properties.Git.UserName, config.Properties.Git.UserEmail),
+		git.WithSigning(config.Properties.Git.SignCommits, config.Properties.Git.GPGKeyID),
+		git.WithFetchDepth(config.Properties.Git.FetchDepth),
+		git.WithGitConfig(gitConfigOptions()),
+		git.WithTotalTimeout(time.Duration(config.Properties.Git.TotalTimeout) * time.Second),
+		git.WithSubmodules(git.SubmoduleOptions{
+			Enabled: config.Properties.Git.UpdateSubmodules,
+			Jobs:    config.Properties.Git.SubmoduleJobs,
+			Depth:   config.Properties.Git.SubmoduleDepth,
+		}),
+		git.WithTag(git.TagOptions{
+			Name:    config.Properties.Git.TagAfterPull,
+			Message: config.Properties.Git.TagMessage,
+			Push:    config.Properties.Git.PushTags,
+			Force:   config.Properties.Git.ForceTag,
+		}),
+	}
+	if config.Properties.Git.RepoTimeout > 0 {
+		opts = append(opts, git.WithRepoTimeout(time.Duration(config.Properties.Git.RepoTimeout)*time.Second))
+	}
+
+	return git.NewUpdateConfig(opts...)
+}
+
+// renderSummaryTemplate executes the Go template in templateFile with the summary of the update and writes it to w.
+// Besides the functions of text/template, the template can use "join" (strings.Join).
+func renderSummaryTemplate(w io.Writer, templateFile string, summary *git.UpdateSummary) error {
+	content, err := os.ReadFile(templateFile)
+	if err != nil {
+		return fmt.Errorf("failed to read output template: %w", err)
+	}
+
+	tmpl, err := template.New(filepath.Base(templateFile)).
+		Funcs(template.FuncMap{"join": strings.Join}).
+		Parse(string(content))
+	if err != nil {
+		return fmt.Errorf("invalid output template %s: %w", templateFile, err)
+	}
+
+	if err := tmpl.Execute(w, summary); err != nil {
+		return fmt.Errorf("failed to render output template %s: %w", templateFile, err)
+	}
+	return nil
+}
+
+// printDryRun writes the repositories that would be updated with their current branch
+func printDryRun(w io.Writer, results []git.RepoResult) {
+	if len(results) == 0 {
+		fmt.Fprintln(w, "No repositories would be updated")
+		return
+	}
+
+	fmt.Fprintf(w, "%-30s %-20s %s\n", "REPOSITORY", "BRANCH", "PATH")
+	for _, result := range results {
+		fmt.Fprintf(w, "%-30s %-20s %s\n", result.Repository, result.Branch, result.Path)
+	}
+}
+
+// printSkippedRepos writes a table with the repositories excluded by the filter and the reason
+func printSkippedRepos(w io.Writer, skipped []git.SkippedRepo) {
+	if len(skipped) == 0 {
+		fmt.Fprintln(w, "No repositories skipped")
+		return
+	}
+
+	fmt.Fprintf(w, "%-30s %s\n", "REPOSITORY", "REASON")
+	for _, repo := range skipped {
+		fmt.Fprintf(w, "%-30s %s\n", repo.Repository, repo.Reason)
+	}
+}
+
+// isHighLevelDir reports whether the absolute path is the root of the file system or the home directory of the user
+func isHighLevelDir(absPath string) bool {
+	if filepath.Dir(absPath) == absPath {
+		return true
+	}
+	home, err := os.UserHomeDir()
+	return err == nil && filepath.Clean(home) == absPath
+}
+
+// validateBaseDir checks the base directory exists, with a specific message when the path is a file
+func validateBaseDir(baseDir string) error {
+	if common.FileExists(baseDir) {
+		return fmt.Errorf("path exists but is a file, not a directory: %s", baseDir)
+	}
+	if !common.DirExists(baseDir) {
+		return fmt.Errorf("directory does not exist: %s", baseDir)
+	}
+	return nil
 }
 
 // initializeFilter creates and configures the repository filter
@@ -110,9 +321,12 @@ func initializeFilter() (*filter.Filter, error) {
 	skipRepos := config.Properties.Filter.SkipRepos
 
 	// Create filter
-	repoFilter, err := filter.NewFilter(skipRepos)
+	repoFilter, err := filter.NewFilter(skipRepos,
+		config.Properties.Filter.IncludePatterns,
+		config.Properties.Filter.ExcludePatterns,
+	)
 	if err != nil {
-		common.Logger("fatal", "Failed to create repository filter: %w", err)
+		return nil, err
 	}
 
 	common.Logger("info", "Repository filter initialized. filter_stats=%v", repoFilter.GetStats())
@@ -132,13 +346,12 @@ func initializeBackupManager() (*backup.BackupManager, error) {
 		backupDir = "./backups"
 	}
 
-	// For now, default to copy strategy
-	strategy := backup.StrategyCopy
-	if config.Properties.Backup.Strategy == "stash" {
-		strategy = backup.StrategyStash
+	backupManager, err := backup.NewBackupManager(backupDir, backup.BackupStrategy(config.Properties.Backup.Strategy))
+	if err != nil {
+		return nil, err
 	}
-
-	backupManager := backup.NewBackupManager(backupDir, strategy)
+	backupManager.ExcludeGitDir = config.Properties.Backup.ExcludeGitDir
+	backupManager.StashDropOnRestore = config.Properties.Backup.StashDropOnRestore
 
 	common.Logger("info", "Backup manager initialized. backup_stats=%v", backupManager.GetBackupStats())
 
@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/aeciopires/updateGit/internal/filter"
+	"github.com/aeciopires/updateGit/internal/git"
+	"github.com/aeciopires/updateGit/internal/gittest"
+)
+
+// resetProperties restores config.Properties to the defaults when the test finishes
+func resetProperties(t *testing.T) {
+	t.Helper()
+
+	reset := func() {
+		config.Properties = config.Config{}
+		config.SetDefaultConfig()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestPullCommand(t *testing.T) {
+	resetProperties(t)
+
+	bareRepo, seedRepo := gittest.NewRemote(t)
+	baseDir := t.TempDir()
+
+	// Clone that updateGit must bring up to date
+	cloneRepo := gittest.Clone(t, bareRepo, baseDir, "project")
+	gittest.CommitFile(t, seedRepo, "README.md", "second", "second commit")
+	gittest.Run(t, seedRepo, "push", "origin", "HEAD:main")
+
+	summary, err := runUpdate(context.Background(), baseDir)
+	if err != nil {
+		t.Fatalf("runUpdate returned error: %v", err)
+	}
+
+	if len(summary.Results) != 1 {
+		t.Fatalf("expected 1 repository result, got %d", len(summary.Results))
+	}
+	if summary.Results[0].Status != git.StatusSuccess {
+		t.Errorf("expected status '%s', got '%s' (error: %s)", git.StatusSuccess, summary.Results[0].Status, summary.Results[0].Error)
+	}
+
+	log := gittest.Run(t, cloneRepo, "log", "--oneline")
+	if !strings.Contains(log, "second commit") {
+		t.Errorf("expected the new commit in the clone log, got:\n%s", log)
+	}
+}
+
+func TestPullCommandUpdateError(t *testing.T) {
+	resetProperties(t)
+
+	bareRepo, _ := gittest.NewRemote(t)
+	baseDir := t.TempDir()
+	gittest.Clone(t, bareRepo, baseDir, "project")
+	config.Properties.Git.BaseDir = baseDir
+
+	// The pull fails without the remote
+	if err := os.RemoveAll(bareRepo); err != nil {
+		t.Fatalf("could not remove remote: %v", err)
+	}
+
+	err := runUpdateCmd.RunE(runUpdateCmd, nil)
+	var updateErr *git.UpdateError
+	if !errors.As(err, &updateErr) {
+		t.Fatalf("expected a *git.UpdateError, got %v", err)
+	}
+	if updateErr.Summary.Failed != 1 {
+		t.Errorf("expected 1 failed repository, got %d", updateErr.Summary.Failed)
+	}
+}
+
+func TestPullCommandIncludePatterns(t *testing.T) {
+	resetProperties(t)
+
+	bareRepo, _ := gittest.NewRemote(t)
+	baseDir := t.TempDir()
+
+	for _, name := range []string{"service-api", "service-web", "tools"} {
+		gittest.Clone(t, bareRepo, baseDir, name)
+	}
+
+	config.Properties.Filter.IncludePatterns = []string{"^service-"}
+	config.Properties.Filter.ExcludePatterns = []string{"-web$"}
+
+	summary, err := runUpdate(context.Background(), baseDir)
+	if err != nil {
+		t.Fatalf("runUpdate returned error: %v", err)
+	}
+
+	if len(summary.Results) != 1 {
+		t.Fatalf("expected 1 repository result, got %d: %+v", len(summary.Results), summary.Results)
+	}
+	if summary.Results[0].Repository != "service-api" {
+		t.Errorf("expected only 'service-api' to be updated, got '%s'", summary.Results[0].Repository)
+	}
+
+	skipped := map[string]string{}
+	for _, repo := range summary.Skipped {
+		skipped[repo.Repository] = repo.Reason
+	}
+	if skipped["service-web"] != filter.ReasonExcludePattern || skipped["tools"] != filter.ReasonIncludeMismatch {
+		t.Errorf("unexpected skipped repositories: %+v", summary.Skipped)
+	}
+}
+
+func TestPrintSkippedRepos(t *testing.T) {
+	var out bytes.Buffer
+	printSkippedRepos(&out, []git.SkippedRepo{{Repository: "tools", Reason: filter.ReasonIncludeMismatch}})
+	if !strings.Contains(out.String(), "REASON") || !strings.Contains(out.String(), "tools") || !strings.Contains(out.String(), filter.ReasonIncludeMismatch) {
+		t.Errorf("unexpected skipped table:\n%s", out.String())
+	}
+
+	out.Reset()
+	printSkippedRepos(&out, nil)
+	if !strings.Contains(out.String(), "No repositories skipped") {
+		t.Errorf("unexpected output without skipped repositories: %q", out.String())
+	}
+}
+
+func TestPullCommandDryRun(t *testing.T) {
+	resetProperties(t)
+	pullDryRun = true
+	t.Cleanup(func() { pullDryRun = false })
+
+	bareRepo, seedRepo := gittest.NewRemote(t)
+	baseDir := t.TempDir()
+
+	cloneRepo := gittest.Clone(t, bareRepo, baseDir, "project")
+	gittest.CommitFile(t, seedRepo, "README.md", "second", "second commit")
+	gittest.Run(t, seedRepo, "push", "origin", "HEAD:main")
+
+	config.Properties.Backup.Enabled = true
+	config.Properties.Backup.Directory = filepath.Join(t.TempDir(), "backups")
+
+	summary, err := runUpdate(context.Background(), baseDir)
+	if err != nil {
+		t.Fatalf("runUpdate returned error: %v", err)
+	}
+
+	if len(summary.Results) != 1 || summary.Results[0].Status != git.StatusDryRun {
+		t.Fatalf("expected 1 repository with status '%s', got %+v", git.StatusDryRun, summary.Results)
+	}
+	if log := gittest.Run(t, cloneRepo, "log", "--oneline"); strings.Contains(log, "second commit") {
+		t.Errorf("the repository was pulled in a dry run:\n%s", log)
+	}
+	if _, err := os.Stat(config.Properties.Backup.Directory); !os.IsNotExist(err) {
+		t.Errorf("the backup directory was created in a dry run")
+	}
+
+	var out bytes.Buffer
+	printDryRun(&out, summary.Results)
+	if !strings.Contains(out.String(), "project") || !strings.Contains(out.String(), "main") {
+		t.Errorf("unexpected dry run output:\n%s", out.String())
+	}
+}
+
+func TestRenderSummaryTemplate(t *testing.T) {
+	summary := &git.UpdateSummary{
+		Total:   2,
+		Success: 1,
+		Failed:  1,
+		Results: []git.RepoResult{
+			{Repository: "repo-a", Path: "/git/repo-a", Branch: "main", Status: git.StatusSuccess, StaleBranches: []string{"old", "gone"}},
+			{Repository: "repo-b", Path: "/git/repo-b", Branch: "dev", Status: git.StatusFailed, Error: "merge conflict"},
+		},
+	}
+
+	var output strings.Builder
+	if err := renderSummaryTemplate(&output, filepath.Join("..", "examples", "templates", "summary.csv.tmpl"), summary); err != nil {
+		t.Fatalf("renderSummaryTemplate() error = %v", err)
+	}
+
+	expected := `repository,path,branch,status,duration_seconds,stale_branches,error
+"repo-a","/git/repo-a","main",success,0.000,"old gone",""
+"repo-b","/git/repo-b","dev",failed,0.000,"","merge conflict"
+`
+	if output.String() != expected {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", output.String(), expected)
+	}
+
+	invalid := filepath.Join(t.TempDir(), "invalid.tmpl")
+	if err := os.WriteFile(invalid, []byte("{{.Total"), config.PermissionFile); err != nil {
+		t.Fatalf("could not write template: %v", err)
+	}
+	if err := renderSummaryTemplate(&output, invalid, summary); err == nil {
+		t.Error("expected an error for an invalid template")
+	}
+}
+
+func TestNewPullUpdateConfigNilProviders(t *testing.T) {
+	resetProperties(t)
+
+	cfg := newPullUpdateConfig(t.TempDir(), nil, nil)
+	if cfg.BackupManager != nil {
+		t.Errorf("expected a nil backup manager interface, got %#v", cfg.BackupManager)
+	}
+	if cfg.Filter != nil {
+		t.Errorf("expected a nil filter interface, got %#v", cfg.Filter)
+	}
+
+	repoFilter, err := initializeFilter()
+	if err != nil {
+		t.Fatalf("initializeFilter failed: %v", err)
+	}
+	config.Properties.Backup.Enabled = true
+	config.Properties.Backup.Directory = t.TempDir()
+	backupManager, err := initializeBackupManager()
+	if err != nil {
+		t.Fatalf("initializeBackupManager failed: %v", err)
+	}
+	cfg = newPullUpdateConfig(t.TempDir(), repoFilter, backupManager)
+	if cfg.BackupManager == nil || cfg.Filter == nil {
+		t.Errorf("expected the backup manager and the filter, got %+v", cfg)
+	}
+}
+
+func TestNewPullUpdateConfigRepoTimeout(t *testing.T) {
+	resetProperties(t)
+
+	cfg := newPullUpdateConfig(t.TempDir(), nil, nil)
+	if cfg.Parallel.Timeout != git.DefaultRepoTimeoutSec*time.Second {
+		t.Errorf("expected the default repository timeout without --git-repo-timeout, got %v", cfg.Parallel.Timeout)
+	}
+
+	config.Properties.Git.RepoTimeout = 300
+	cfg = newPullUpdateConfig(t.TempDir(), nil, nil)
+	if cfg.Parallel.Timeout != 300*time.Second {
+		t.Errorf("expected the timeout of --git-repo-timeout, got %v", cfg.Parallel.Timeout)
+	}
+}
+
+func TestNewPullUpdateConfigTotalTimeout(t *testing.T) {
+	resetProperties(t)
+
+	cfg := newPullUpdateConfig(t.TempDir(), nil, nil)
+	if cfg.TotalTimeout != git.DefaultTotalTimeoutSec*time.Second {
+		t.Errorf("expected the default total timeout without --git-total-timeout, got %v", cfg.TotalTimeout)
+	}
+
+	config.Properties.Git.TotalTimeout = 0
+	cfg = newPullUpdateConfig(t.TempDir(), nil, nil)
+	if cfg.TotalTimeout != 0 {
+		t.Errorf("expected no total timeout with --git-total-timeout=0, got %v", cfg.TotalTimeout)
+	}
+}
+
+func TestValidateBaseDir(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "repos.txt")
+	if err := os.WriteFile(file, nil, config.PermissionFile); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		baseDir string
+		errText string
+	}{
+		{name: "directory", baseDir: t.TempDir()},
+		{name: "missing directory", baseDir: filepath.Join(t.TempDir(), "missing"), errText: "directory does not exist"},
+		{name: "regular file", baseDir: file, errText: "is a file, not a directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBaseDir(tt.baseDir)
+			if tt.errText == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errText) {
+				t.Errorf("expected an error containing %q, got %v", tt.errText, err)
+			}
+		})
+	}
+}
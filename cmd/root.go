@@ -5,13 +5,16 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/aeciopires/updateGit/internal/common"
 	"github.com/aeciopires/updateGit/internal/config"
 	"github.com/aeciopires/updateGit/internal/getinfo"
+	"github.com/aeciopires/updateGit/internal/git"
 	"github.com/go-playground/validator/v10"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
@@ -21,6 +24,10 @@ var (
 	shortVersion *bool
 )
 
+// skipConfigLoadError is the annotation of the commands that report the error of loadConfig themselves
+// or do not use the loaded configuration. For the other commands, the error is fatal
+const skipConfigLoadError = "skipConfigLoadError"
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "updateGit",
@@ -29,6 +36,16 @@ var rootCmd = &cobra.Command{
 
 This tool scans a base directory for git repositories and runs 'git pull'
 on each one to keep them up to date.`,
+	// PersistentPreRunE is executed before every subcommand
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// A missing default config file is fine, but a file informed by the user must exist
+		if flag := cmd.Flag("config-file"); flag != nil && flag.Changed && !common.FileExists(config.Properties.DefaultConfigFile) {
+			// The usage is not useful to fix the path
+			cmd.SilenceUsage = true
+			return fmt.Errorf("config file not found: %s", config.Properties.DefaultConfigFile)
+		}
+		return nil
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		// If the user ran the command without providing any arguments and without setting any flags.
 		// If both of those conditions are met, it assumes the user needs help and displays the command's help text.
@@ -41,17 +58,16 @@ on each one to keep them up to date.`,
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// The exit code is 1 if the command returns an error, e.g. the *git.UpdateError of the pull command.
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
 		os.Exit(1)
 	}
 
-	// Show longVersion. *longVersion contains the pointer address. If the content is true print longVersion, system and arch
+	// Show longVersion. *longVersion contains the pointer address. If the content is true print longVersion, system, arch, git and config file
 	if *longVersion {
 		getinfo.PrintLongVersion()
-		getinfo.ShowOperatingSystem()
-		getinfo.ShowSystemArch()
 	}
 
 	// Show shortVersion. *shortVersion contains the pointer address. If the content is true print shortVersion, system and arch
@@ -74,7 +90,12 @@ func Execute() {
 
 func init() {
 	config.SetDefaultConfig()
-	cobra.OnInitialize(initConfig)
+	cobra.OnInitialize(func() {
+		configLoadError = loadConfig()
+		if configLoadError != nil && !skipsConfigLoadError(rootCmd) {
+			common.Logger("fatal", "%v", configLoadError)
+		}
+	})
 
 	// Global flags
 	// Here you will define your flags and configuration settings.
@@ -90,19 +111,103 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&config.Properties.Git.BaseDir, "git-base-dir", "G", config.Properties.Git.BaseDir, "Base directory for git repositories")
 	rootCmd.PersistentFlags().BoolVarP(&config.Properties.Git.Parallel, "git-parallel-enabled", "P", config.Properties.Git.Parallel, "Enable parallel git repository updates")
 	rootCmd.PersistentFlags().IntVarP(&config.Properties.Git.MaxConcurrent, "git-max-concurrent", "J", config.Properties.Git.MaxConcurrent, "Maximum number of concurrent git repositories updates")
+	rootCmd.PersistentFlags().IntVar(&config.Properties.Git.RepoTimeout, "git-repo-timeout", config.Properties.Git.RepoTimeout, "Seconds the pull of each repository can take before it is killed, login/password prompts included. 0 uses the default (60)")
+	rootCmd.PersistentFlags().IntVar(&config.Properties.Git.TotalTimeout, "git-total-timeout", config.Properties.Git.TotalTimeout, "Seconds the pull of all repositories can take before the running pulls are killed. 0 means no limit")
+	rootCmd.PersistentFlags().IntVar(&config.Properties.Git.MaxDepth, "git-scan-depth", config.Properties.Git.MaxDepth, "Levels of directories scanned for repositories below the base directory, from 0 to 20. 0 means unlimited (use with caution)")
+	rootCmd.PersistentFlags().StringVar(&config.Properties.Git.CommitMessageTemplate, "git-commit-message-template", config.Properties.Git.CommitMessageTemplate, "Go template for the message of merge commits created by pull (e.g. 'Sync {{.Name}} ({{.CurrentBranch}})')")
+	rootCmd.PersistentFlags().StringArrayVar(&config.Properties.Git.ExtraPullArgs, "git-extra-args", config.Properties.Git.ExtraPullArgs, "Extra argument passed to git pull (can be repeated, e.g. --git-extra-args=--verify-signatures)")
+	rootCmd.PersistentFlags().StringVar(&config.Properties.Git.PullStrategy, "git-pull-strategy", config.Properties.Git.PullStrategy, "Strategy of git pull: 'merge', 'rebase' or 'ff-only'. Empty keeps the git configuration (pull.rebase, pull.ff)")
+	rootCmd.PersistentFlags().IntVar(&config.Properties.Git.MaxPackSize, "git-max-pack-size", config.Properties.Git.MaxPackSize, "Maximum memory in MiB used by git to handle packs (pack.windowMemory). 0 keeps the git configuration")
+	rootCmd.PersistentFlags().IntVar(&config.Properties.Git.HTTPLowSpeedLimit, "git-http-low-speed-limit", config.Properties.Git.HTTPLowSpeedLimit, "Abort git HTTP transfers slower than this value in bytes per second (http.lowSpeedLimit). 0 keeps the git configuration")
+	rootCmd.PersistentFlags().IntVar(&config.Properties.Git.HTTPLowSpeedTime, "git-http-low-speed-time", config.Properties.Git.HTTPLowSpeedTime, "Seconds below --git-http-low-speed-limit before git aborts the transfer (http.lowSpeedTime). 0 keeps the git configuration")
+	rootCmd.PersistentFlags().StringVar(&config.Properties.Git.SafeDirectory, "git-safe-directory", config.Properties.Git.SafeDirectory, "Absolute path of a repository owned by another user that git can use (safe.directory). '*' trusts all repositories")
+	rootCmd.PersistentFlags().StringVar(&config.Properties.Git.CredentialHelper, "git-credential-helper", config.Properties.Git.CredentialHelper, "Credential helper of HTTPS remotes (credential.helper), e.g. 'cache', 'store' (plaintext file) or 'osxkeychain'")
+	rootCmd.PersistentFlags().BoolVar(&config.Properties.Git.SignCommits, "git-sign-commits", config.Properties.Git.SignCommits, "GPG-sign the merge commits created by pull")
+	rootCmd.PersistentFlags().StringVar(&config.Properties.Git.GPGKeyID, "git-gpg-key-id", config.Properties.Git.GPGKeyID, "GPG key ID used by --git-sign-commits (default is the key of the git configuration)")
+	rootCmd.PersistentFlags().IntVar(&config.Properties.Git.FetchDepth, "git-fetch-depth", config.Properties.Git.FetchDepth, "Depth of the git fetch executed before the pull (0 means not limited). The pull is not affected")
+	rootCmd.PersistentFlags().StringVar(&config.Properties.Git.UserName, "git-user-name", config.Properties.Git.UserName, "Author name of the merge commits created by pull (default is user.name of the git configuration)")
+	rootCmd.PersistentFlags().StringVar(&config.Properties.Git.UserEmail, "git-user-email", config.Properties.Git.UserEmail, "Author email of the merge commits created by pull (default is user.email of the git configuration)")
+	rootCmd.PersistentFlags().StringVar(&config.Properties.Git.TagAfterPull, "git-tag-after-pull", config.Properties.Git.TagAfterPull, "Create an annotated tag at HEAD after a successful pull. Go template (e.g. 'sync-{{.CurrentBranch}}')")
+	rootCmd.PersistentFlags().StringVar(&config.Properties.Git.TagMessage, "git-tag-message", config.Properties.Git.TagMessage, "Message of the tag created by --git-tag-after-pull. Go template (e.g. 'Sync of {{.Name}}')")
+	rootCmd.PersistentFlags().BoolVar(&config.Properties.Git.PushTags, "git-push-tags", config.Properties.Git.PushTags, "Push the tag created by --git-tag-after-pull to origin")
+	rootCmd.PersistentFlags().BoolVar(&config.Properties.Git.ForceTag, "git-force-tag", config.Properties.Git.ForceTag, "Replace the tag created by --git-tag-after-pull if it already exists")
+	rootCmd.PersistentFlags().BoolVar(&config.Properties.Git.WorktreePrune, "git-worktree-prune", config.Properties.Git.WorktreePrune, "Run 'git worktree prune' after each pull to remove references to deleted worktrees")
+	rootCmd.PersistentFlags().BoolVar(&config.Properties.Git.IncludeSubmoduleRepos, "git-include-submodule-repos", config.Properties.Git.IncludeSubmoduleRepos, "Update the repositories that are submodules of the base directory repository (skipped by default)")
+	rootCmd.PersistentFlags().BoolVar(&config.Properties.Git.PullOnlyIfBehind, "git-pull-only-if-behind", config.Properties.Git.PullOnlyIfBehind, "Fetch first and skip the pull of repositories whose upstream has no new commits")
+	rootCmd.PersistentFlags().BoolVarP(&config.Properties.Git.AutoStash, "auto-stash", "A", config.Properties.Git.AutoStash, "Stash the uncommitted changes before the pull and restore them after it. On conflicts the changes are kept in the stash")
+	rootCmd.PersistentFlags().BoolVar(&config.Properties.Git.PostPullDiffStat, "git-post-pull-diff-stat", config.Properties.Git.PostPullDiffStat, "Show the diff stat (git diff --stat) of the commits added by each pull")
+	rootCmd.PersistentFlags().BoolVar(&config.Properties.Git.FetchAllRemotes, "git-fetch-all-remotes", config.Properties.Git.FetchAllRemotes, "Fetch every remote of the repository (e.g. upstream, fork) before the pull")
+	rootCmd.PersistentFlags().BoolVar(&config.Properties.Git.FetchPrune, "git-fetch-prune", config.Properties.Git.FetchPrune, "Remove the remote-tracking branches deleted from the remotes in the fetch of --git-fetch-all-remotes and --fetch-before-pull")
+	rootCmd.PersistentFlags().BoolVar(&config.Properties.Git.FetchBeforePull, "fetch-before-pull", config.Properties.Git.FetchBeforePull, "Run 'git fetch --all' before the pull of each repository (the pull fetches only the upstream of the current branch)")
+	rootCmd.PersistentFlags().BoolVar(&config.Properties.Git.UpdateSubmodules, "git-update-submodules", config.Properties.Git.UpdateSubmodules, "Run 'git submodule update --init --recursive' after each successful pull")
+	rootCmd.PersistentFlags().IntVar(&config.Properties.Git.SubmoduleJobs, "git-submodule-jobs", config.Properties.Git.SubmoduleJobs, "Number of submodules fetched in parallel by --git-update-submodules (0 uses the git default)")
+	rootCmd.PersistentFlags().IntVar(&config.Properties.Git.SubmoduleDepth, "git-submodule-depth", config.Properties.Git.SubmoduleDepth, "Number of commits fetched for each submodule by --git-update-submodules (0 fetches the full history)")
 
 	// Backup flags
 	rootCmd.PersistentFlags().BoolVarP(&config.Properties.Backup.Enabled, "backup-enabled", "B", config.Properties.Backup.Enabled, "Create backup before updating")
 	rootCmd.PersistentFlags().StringVarP(&config.Properties.Backup.Directory, "backup-dir", "Z", config.Properties.Backup.Directory, "Directory to store backups")
-	rootCmd.PersistentFlags().StringVarP(&config.Properties.Backup.Strategy, "backup-strategy", "Y", config.Properties.Backup.Strategy, "Backup strategy (e.g. 'copy', 'stash')")
+	rootCmd.PersistentFlags().StringVarP(&config.Properties.Backup.Strategy, "backup-strategy", "Y", config.Properties.Backup.Strategy, "Backup strategy (e.g. 'copy', 'stash', 'auto', 'bundle'). 'auto' uses 'stash' for repositories with uncommitted changes and 'copy' for the others. 'bundle' saves the committed history in a git bundle file")
+	rootCmd.PersistentFlags().BoolVar(&config.Properties.Backup.ExcludeGitDir, "backup-exclude-git-dir", config.Properties.Backup.ExcludeGitDir, "Skip the .git directory in copy backups. Use --backup-exclude-git-dir=false to keep the history (bigger backups)")
+	rootCmd.PersistentFlags().BoolVar(&config.Properties.Backup.StashDropOnRestore, "backup-stash-drop-on-restore", config.Properties.Backup.StashDropOnRestore, "Drop the stash entry after a stash backup is restored (by default it is kept)")
+	rootCmd.PersistentFlags().IntVar(&config.Properties.Backup.RetentionDays, "retention-days", config.Properties.Backup.RetentionDays, "Remove the backups older than the number of days after a successful pull. 0 keeps all backups")
 
 	// Filtering flags
 	rootCmd.PersistentFlags().StringSliceVarP(&config.Properties.Filter.SkipRepos, "skip-repos", "S", config.Properties.Filter.SkipRepos, "List of repository names to skip")
+	rootCmd.PersistentFlags().StringSliceVar(&config.Properties.Filter.IncludePatterns, "include-patterns", config.Properties.Filter.IncludePatterns, "Regular expressions of repository names to process. A repository matching any of them is processed")
+	rootCmd.PersistentFlags().StringSliceVar(&config.Properties.Filter.ExcludePatterns, "exclude-patterns", config.Properties.Filter.ExcludePatterns, "Regular expressions of repository names to skip. It has priority over --include-patterns")
+	rootCmd.PersistentFlags().BoolVar(&config.Properties.Filter.ListSkipped, "list-skipped", config.Properties.Filter.ListSkipped, "Print the repositories excluded by the filter and the reason after the pull")
+
+	// Output flags
+	rootCmd.PersistentFlags().StringVarP(&config.Properties.Output.Format, "output", "o", config.Properties.Output.Format, "Output format of the status and list commands (e.g. 'text', 'json', 'yaml', 'csv')")
+	rootCmd.PersistentFlags().StringVar(&config.Properties.Output.LogFormat, "log-format", config.Properties.Output.LogFormat, "Log format (e.g. 'console', 'json')")
+	rootCmd.PersistentFlags().StringVar(&config.Properties.Output.LogFile, "log-file", config.Properties.Output.LogFile, "Write log messages to this file instead of stdout")
+	rootCmd.PersistentFlags().BoolVarP(&config.Properties.Output.Quiet, "quiet", "q", config.Properties.Output.Quiet, "Show only warning and error messages")
+	rootCmd.PersistentFlags().BoolVar(&config.Properties.Output.Color, "color", config.Properties.Output.Color, "Enable colored log output")
+	rootCmd.PersistentFlags().StringVar(&config.Properties.Output.LogTimestampFormat, "log-timestamp-format", config.Properties.Output.LogTimestampFormat, "Timestamp format of log messages: a Go time layout (e.g. '2006-01-02 15:04:05') or 'unix' for Unix epoch seconds")
+	rootCmd.PersistentFlags().StringVar(&config.Properties.Output.LogTimezone, "log-timezone", config.Properties.Output.LogTimezone, "Timezone of log timestamps: 'local', 'utc' or an IANA timezone name (e.g. 'America/New_York')")
+	rootCmd.PersistentFlags().StringVar(&config.Properties.Output.Template, "output-template", config.Properties.Output.Template, "Go template file rendered with the summary of the pull (see examples/templates)")
+
+	registerEnumFlagCompletions()
 }
 
-// initConfig reads in config file and ENV variables if set.
+// gitConfigOptions returns the git configuration keys of the properties passed to the git commands
+func gitConfigOptions() git.ConfigOptions {
+	return git.ConfigOptions{
+		MaxPackSize:       config.Properties.Git.MaxPackSize,
+		HTTPLowSpeedLimit: config.Properties.Git.HTTPLowSpeedLimit,
+		HTTPLowSpeedTime:  config.Properties.Git.HTTPLowSpeedTime,
+		SafeDirectory:     config.Properties.Git.SafeDirectory,
+		CredentialHelper:  config.Properties.Git.CredentialHelper,
+	}
+}
+
+// skipsConfigLoadError reports if the command being executed, cmd or one of its subcommands,
+// has the skipConfigLoadError annotation
+func skipsConfigLoadError(cmd *cobra.Command) bool {
+	if cmd.CalledAs() != "" {
+		return cmd.Annotations[skipConfigLoadError] == "true"
+	}
+	for _, child := range cmd.Commands() {
+		if skipsConfigLoadError(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkGitConfigEnvSupport returns an error if the git binary is too old for the git settings of the properties
+func checkGitConfigEnvSupport() error {
+	return git.CheckConfigEnvSupport(
+		gitConfigOptions(),
+		git.CommitIdentity{Name: config.Properties.Git.UserName, Email: config.Properties.Git.UserEmail},
+		git.SigningOptions{Enabled: config.Properties.Git.SignCommits, KeyID: config.Properties.Git.GPGKeyID},
+	)
+}
+
+// loadConfig reads in config file and ENV variables if set.
 // This function is performaded in cmd/root.go and cmd/subcommand.go
-func initConfig() {
+// It returns an error instead of exiting, so the caller decides how to handle it.
+func loadConfig() error {
 	// Environment variables expect with prefix CLI_ . This helps avoid conflicts.
 	viper.SetEnvPrefix("cli")
 	// Type file
@@ -111,16 +216,73 @@ func initConfig() {
 	// keys with underscores, e.g. --backup-enabled to CLI_BACKUP_ENABLED
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_", ".", "_"))
 
-  // Bind nested keys to ENV vars
-	bindEnvs(
+	// Flags set in the command line have priority over the config file and the environment variables
+	commandLine := changedFlags()
+
+	// Register defaults so Viper's priority chain keeps them
+	config.SetViperDefaults()
+
+	// Bind nested keys to ENV vars
+	failedEnvBindings := bindEnvs(
 		"debug",
 		"git.base_dir",
 		"git.parallel_enabled",
 		"git.max_concurrent",
+		"git.repo_timeout",
+		"git.total_timeout",
+		"git.scan_depth",
+		"git.clone_depth",
+		"git.clone_branch",
+		"git.clone_single_branch",
+		"git.commit_message_template",
+		"git.extra_pull_args",
+		"git.pull_strategy",
+		"git.max_pack_size",
+		"git.http_low_speed_limit",
+		"git.http_low_speed_time",
+		"git.credential_helper",
+		"git.safe_directory",
+		"git.sign_commits",
+		"git.gpg_key_id",
+		"git.fetch_depth",
+		"git.user_name",
+		"git.user_email",
+		"git.tag_after_pull",
+		"git.tag_message",
+		"git.push_tags",
+		"git.force_tag",
+		"git.worktree_prune",
+		"git.include_submodule_repos",
+		"git.pull_only_if_behind",
+		"git.auto_stash",
+		"git.post_pull_diff_stat",
+		"git.fetch_all_remotes",
+		"git.fetch_prune",
+		"git.fetch_before_pull",
+		"git.update_submodules",
+		"git.submodule_jobs",
+		"git.submodule_depth",
 		"backup.enabled",
 		"backup.directory",
 		"backup.strategy",
+		"backup.exclude_git_dir",
+		"backup.stash_drop_on_restore",
+		"backup.retention_days",
 		"filter.skip_repos",
+		"filter.include_patterns",
+		"filter.exclude_patterns",
+		"filter.list_skipped",
+		"output.format",
+		"output.log_format",
+		"output.log_file",
+		"output.quiet",
+		"output.color",
+		"output.log_timestamp_format",
+		"output.log_timezone",
+		"output.template",
+		"hooks.pre_pull",
+		"hooks.post_pull",
+		"hooks.strict_mode",
 	)
 
 	// Attempt to read the SPECIFIC config file (passed by default value or -c option)
@@ -137,14 +299,11 @@ func initConfig() {
 		// FAILURE reading specific file - Log details and attempt fallback
 		common.Logger("debug", "Could not read specific config file '%s': %v\n", viper.ConfigFileUsed(), err)
 		// Check if the error was specifically "file not found"
-		var configFileNotFoundError viper.ConfigFileNotFoundError
-		if errors.As(err, &configFileNotFoundError) {
-			common.Logger("debug", "Specific config file not found. Falling back to search for '.updateGit.yaml' file.")
-		} else {
+		if !isConfigFileNotFound(err) {
 			// A different error occurred (permissions, format, etc.)
-			common.Logger("debug", "Error occurred while reading specific config file '%s'.: %v\n", viper.ConfigFileUsed(), err)
-			common.Logger("debug", "Check %v file permissions and format.", viper.ConfigFileUsed())
+			return fmt.Errorf("error occurred while reading config file '%s'. Check file permissions and format: %w", viper.ConfigFileUsed(), err)
 		}
+		common.Logger("debug", "Specific config file not found. Falling back to search for '.updateGit.yaml' file.")
 
 		// Configure and attempt fallback search for ".updateGit.yaml"
 		common.Logger("debug", "Setting up fallback search for '.updateGit.yaml' in paths: '.', '/app'")
@@ -159,26 +318,28 @@ func initConfig() {
 			common.Logger("debug", "Using fallback config file: %v", viper.ConfigFileUsed())
 		} else {
 			// FAILURE reading fallback .updateGit.yaml file
-			if errors.As(fallbackErr, &configFileNotFoundError) {
-				// This is expected if no .updateGit.yaml file exists in the search paths
-				common.Logger("debug", "No '.updateGit.yaml' config file found in search paths either. Using defaults and environment variables.")
-			} else {
+			if !isConfigFileNotFound(fallbackErr) {
 				// An error occurred reading the fallback .updateGit.yaml file (permissions, format?)
-				common.Logger("debug", "Error reading fallback '.updateGit.yaml' file: %v\n", fallbackErr)
-				common.Logger("debug", "Check %v file permissions and format.", viper.ConfigFileUsed())
+				return fmt.Errorf("error reading fallback config file '%s'. Check file permissions and format: %w", viper.ConfigFileUsed(), fallbackErr)
 			}
+			// This is expected if no .updateGit.yaml file exists in the search paths
+			common.Logger("debug", "No '.updateGit.yaml' config file found in search paths either. Using defaults and environment variables.")
 		}
 	}
 
 	// Read in environment variables that match Viper keys or have the CLI_ prefix
-	// Read environment variables *now*. They might be overridden by config file.
+	// Environment variables override the config file.
 	viper.AutomaticEnv()
 
 	// Unmarshal the final configuration
-	// Viper now contains the merged view: Defaults overridden by Env Vars overridden by (potentially) a loaded Config File.
+	// Viper now contains the merged view: Defaults overridden by (potentially) a loaded Config File overridden by Env Vars.
+	// The flags set in the command line are applied again on top of it.
 	common.Logger("debug", "Unmarshaling final configuration into struct.")
 	if err := viper.Unmarshal(&config.Properties); err != nil {
-		common.Logger("fatal", "Error unmarshaling config: %s", err)
+		return fmt.Errorf("error unmarshaling config: %w", err)
+	}
+	if err := commandLine.apply(); err != nil {
+		return err
 	}
 
 	// Validate the populated struct
@@ -187,6 +348,10 @@ func initConfig() {
 	validate := validator.New(validator.WithRequiredStructEnabled())
 	// Register custom validators
 	validate.RegisterValidation("noUnderscore", config.NoUnderscores)
+	validate.RegisterValidation("notManagedPullArg", config.NotManagedPullArg)
+	validate.RegisterValidation("timeLayout", config.TimeLayout)
+	validate.RegisterValidation("timezone", config.Timezone)
+	validate.RegisterValidation("executable", common.Executable)
 
 	// Validate the Properties struct (pass by reference)
 	if err := validate.Struct(&config.Properties); err != nil {
@@ -202,25 +367,98 @@ func initConfig() {
 					fieldErr.Value(),           // The actual invalid value
 				)
 			}
-			// Log as fatal error and exit
-			common.Logger("fatal", "%s", errorMsg)
-		} else {
-			// Handle other potential errors during validation itself (less common)
-			common.Logger("fatal", "An unexpected error occurred during configuration validation: %s", err)
+			return errors.New(strings.TrimSpace(errorMsg))
 		}
+		// Handle other potential errors during validation itself (less common)
+		return fmt.Errorf("an unexpected error occurred during configuration validation: %w", err)
 	}
 
 	// Optional: Log the final loaded configuration for verification
 	finalConfigBytes, _ := yaml.Marshal(config.Properties) // Or use json.MarshalIndent
 	common.Logger("debug", "Final Configuration Loaded:\n%s\n", string(finalConfigBytes))
 
+	if err := checkGitConfigEnvSupport(); err != nil {
+		return err
+	}
+	// Used by the git commands of all subcommands, e.g. status and clone
+	git.SetConfigOptions(gitConfigOptions())
+
+	if config.Properties.Git.SafeDirectory == "*" {
+		common.Logger("warning", "The git ownership check is disabled for all repositories (--git-safe-directory='*'). Git hooks and settings of repositories owned by other users are trusted.")
+	}
+
+	// Environment variable overrides do not work for these keys
+	if len(failedEnvBindings) > 0 {
+		common.Logger("warning", "Environment variables could not be bound for keys: %s. Overrides via CLI_* variables are ignored for them.", strings.Join(failedEnvBindings, ", "))
+	}
+
+	return nil
+}
+
+// isConfigFileNotFound reports if the error means the config file does not exist.
+// Viper returns ConfigFileNotFoundError when searching paths and a fs error when the file path is explicit.
+func isConfigFileNotFound(err error) bool {
+	var configFileNotFoundError viper.ConfigFileNotFoundError
+	return errors.As(err, &configFileNotFoundError) || errors.Is(err, os.ErrNotExist)
+}
+
+// flagValues holds the values of flags set in the command line
+type flagValues map[*pflag.Flag][]string
+
+// changedFlags returns the values of the flags of all commands set in the command line.
+// The values are read from the variables bound to the flags, so it must be called before
+// they are overwritten by the config file
+func changedFlags() flagValues {
+	values := flagValues{}
+	var visit func(cmd *cobra.Command)
+	visit = func(cmd *cobra.Command) {
+		for _, flags := range []*pflag.FlagSet{cmd.PersistentFlags(), cmd.Flags()} {
+			flags.VisitAll(func(flag *pflag.Flag) {
+				if !flag.Changed {
+					return
+				}
+				if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+					// The config file is decoded into the same backing array
+					values[flag] = slices.Clone(sliceValue.GetSlice())
+				} else {
+					values[flag] = []string{flag.Value.String()}
+				}
+			})
+		}
+		for _, child := range cmd.Commands() {
+			visit(child)
+		}
+	}
+	visit(rootCmd)
+	return values
+}
+
+// apply sets the flag values again, writing them to the bound variables
+func (values flagValues) apply() error {
+	for flag, value := range values {
+		var err error
+		if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+			// Set appends to slices already set
+			err = sliceValue.Replace(value)
+		} else {
+			err = flag.Value.Set(value[0])
+		}
+		if err != nil {
+			return fmt.Errorf("could not apply flag --%s: %w", flag.Name, err)
+		}
+	}
+	return nil
 }
 
 // helper to bind nested keys to ENV vars
-func bindEnvs(keys ...string) {
+// It returns the keys that could not be bound.
+func bindEnvs(keys ...string) []string {
+	var failedKeys []string
 	for _, key := range keys {
 		if err := viper.BindEnv(key); err != nil {
-			common.Logger("debug", "Could not bind env for key %s: %v", key, err)
+			common.Logger("warning", "Could not bind env for key %s: %v", key, err)
+			failedKeys = append(failedKeys, key)
 		}
 	}
+	return failedKeys
 }
@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// setupConfigFile writes content to a temporary config file and points the CLI to it.
+// Viper and config.Properties are restored to the defaults when the test finishes.
+func setupConfigFile(t *testing.T, content string) string {
+	t.Helper()
+
+	resetConfig := func() {
+		viper.Reset()
+		config.Properties = config.Config{}
+		config.SetDefaultConfig()
+	}
+	resetConfig()
+	t.Cleanup(resetConfig)
+
+	configFile := filepath.Join(t.TempDir(), "updateGit.yaml")
+	if err := os.WriteFile(configFile, []byte(content), config.PermissionFile); err != nil {
+		t.Fatalf("could not write config file: %v", err)
+	}
+	config.Properties.DefaultConfigFile = configFile
+
+	return configFile
+}
+
+func TestLoadConfigValidFile(t *testing.T) {
+	setupConfigFile(t, `
+git:
+  base_dir: "/tmp/repos"
+  max_concurrent: 3
+backup:
+  strategy: "stash"
+`)
+
+	if err := loadConfig(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if config.Properties.Git.BaseDir != "/tmp/repos" {
+		t.Errorf("expected base_dir '/tmp/repos', got '%s'", config.Properties.Git.BaseDir)
+	}
+	if config.Properties.Git.MaxConcurrent != 3 {
+		t.Errorf("expected max_concurrent 3, got %d", config.Properties.Git.MaxConcurrent)
+	}
+	if config.Properties.Backup.Strategy != "stash" {
+		t.Errorf("expected strategy 'stash', got '%s'", config.Properties.Backup.Strategy)
+	}
+}
+
+func TestLoadConfigMissingFileUsesDefaults(t *testing.T) {
+	configFile := setupConfigFile(t, "")
+	config.Properties.DefaultConfigFile = configFile + ".missing"
+
+	if err := loadConfig(); err != nil {
+		t.Fatalf("expected no error for a missing config file, got: %v", err)
+	}
+
+	if config.Properties.Git.BaseDir != "./git_repos" {
+		t.Errorf("expected default base_dir, got '%s'", config.Properties.Git.BaseDir)
+	}
+}
+
+func TestLoadConfigBadFormat(t *testing.T) {
+	setupConfigFile(t, "git:\n  base_dir: [unclosed\n")
+
+	err := loadConfig()
+	if err == nil {
+		t.Fatal("expected an error for a malformed config file")
+	}
+	if !strings.Contains(err.Error(), "Check file permissions and format") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestLoadConfigValidationFailure(t *testing.T) {
+	setupConfigFile(t, `
+backup:
+  strategy: "tarball"
+`)
+
+	err := loadConfig()
+	if err == nil {
+		t.Fatal("expected a validation error for an unknown backup strategy")
+	}
+	if !strings.Contains(err.Error(), "Config.Backup.Strategy") || !strings.Contains(err.Error(), "oneof") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+// setFlag sets a flag of the root command as if it was passed in the command line
+func setFlag(t *testing.T, name, value string) {
+	t.Helper()
+
+	flag := rootCmd.PersistentFlags().Lookup(name)
+	t.Cleanup(func() { flag.Changed = false })
+	if err := rootCmd.PersistentFlags().Set(name, value); err != nil {
+		t.Fatalf("could not set flag --%s: %v", name, err)
+	}
+}
+
+func TestLoadConfigFlagPriority(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+	}{
+		{name: "flag overrides config file"},
+		{name: "flag overrides environment variable", env: "4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupConfigFile(t, `
+git:
+  max_concurrent: 3
+filter:
+  skip_repos: ["from-file"]
+`)
+			if tt.env != "" {
+				t.Setenv("CLI_GIT_MAX_CONCURRENT", tt.env)
+				t.Setenv("CLI_FILTER_SKIP_REPOS", "from-env")
+			}
+			setFlag(t, "git-max-concurrent", "7")
+			setFlag(t, "skip-repos", "from-flag")
+
+			// The config is loaded twice, as in watch mode
+			for range 2 {
+				if err := loadConfig(); err != nil {
+					t.Fatalf("loadConfig returned error: %v", err)
+				}
+			}
+
+			if config.Properties.Git.MaxConcurrent != 7 {
+				t.Errorf("expected max_concurrent 7 of the flag, got %d", config.Properties.Git.MaxConcurrent)
+			}
+			if skipRepos := config.Properties.Filter.SkipRepos; len(skipRepos) != 1 || skipRepos[0] != "from-flag" {
+				t.Errorf("expected skip_repos [from-flag] of the flag, got %v", skipRepos)
+			}
+		})
+	}
+}
+
+func TestSkipsConfigLoadError(t *testing.T) {
+	for _, cmd := range []*cobra.Command{checkCmd, initCmd, configValidateCmd, configDumpCmd} {
+		if cmd.Annotations[skipConfigLoadError] != "true" {
+			t.Errorf("expected the %s annotation in the %s command", skipConfigLoadError, cmd.CommandPath())
+		}
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected bool
+	}{
+		{name: "annotated subcommand", args: []string{"group", "report"}, expected: true},
+		{name: "subcommand without annotation", args: []string{"group", "run"}, expected: false},
+		{name: "root command", args: []string{}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Execute runs the cobra.OnInitialize functions, which load this config file
+			setupConfigFile(t, "")
+
+			var skips bool
+			record := func(*cobra.Command, []string) {}
+			root := &cobra.Command{Use: "root", Run: record}
+			group := &cobra.Command{Use: "group"}
+			group.AddCommand(
+				&cobra.Command{Use: "report", Run: record, Annotations: map[string]string{skipConfigLoadError: "true"}},
+				&cobra.Command{Use: "run", Run: record},
+			)
+			root.AddCommand(group)
+			root.PersistentPreRun = func(*cobra.Command, []string) { skips = skipsConfigLoadError(root) }
+
+			root.SetArgs(tt.args)
+			if err := root.Execute(); err != nil {
+				t.Fatalf("Execute returned error: %v", err)
+			}
+			if skips != tt.expected {
+				t.Errorf("skipsConfigLoadError() = %t, expected %t", skips, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRootCommandMissingConfigFile(t *testing.T) {
+	configFile := setupConfigFile(t, "")
+	missingFile := configFile + ".missing"
+
+	var output bytes.Buffer
+	rootCmd.SetArgs([]string{"config", "validate", "--config-file", missingFile})
+	rootCmd.SetOut(&output)
+	rootCmd.SetErr(&output)
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.PersistentFlags().Lookup("config-file").Changed = false
+	})
+
+	err := rootCmd.Execute()
+	if err == nil {
+		t.Fatal("expected an error for a missing --config-file")
+	}
+	if expected := "config file not found: " + missingFile; err.Error() != expected {
+		t.Errorf("expected error %q, got %q", expected, err.Error())
+	}
+	if !strings.Contains(output.String(), "Error: config file not found") || strings.Contains(output.String(), "Usage:") {
+		t.Errorf("expected the error without the usage, got:\n%s", output.String())
+	}
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aeciopires/updateGit/internal/common"
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/aeciopires/updateGit/internal/git"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// repoStatus is the state of one repository reported by the status command
+type repoStatus struct {
+	Repository string `json:"repository" yaml:"repository"`
+	Path       string `json:"path" yaml:"path"`
+	Branch     string `json:"branch" yaml:"branch"`
+	Detached   bool   `json:"detached" yaml:"detached"`
+	Dirty      bool   `json:"dirty" yaml:"dirty"`
+	// HasUpstream is false when the branch does not track a remote branch, so Behind is not known
+	HasUpstream bool `json:"has_upstream" yaml:"has_upstream"`
+	Behind      int  `json:"behind" yaml:"behind"`
+	// Tags are the tags pointing to HEAD
+	Tags []string `json:"tags" yaml:"tags"`
+	// StaleBranches are the local branches whose upstream branch was deleted from the remote
+	StaleBranches []string `json:"stale_branches" yaml:"stale_branches"`
+}
+
+var (
+	// statusCmd reports the state of the repositories without changing them
+	statusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Show the state of the git repositories without updating them",
+		Long: `Show the branch of each repository in the base directory, whether it has
+uncommitted changes or a detached HEAD, how many commits it is behind its upstream,
+the tags pointing to HEAD and the stale tracking branches, whose upstream branch
+was deleted from the remote. Use the prune command to delete them.
+
+Nothing is fetched, so the commits behind are counted against the last fetch.
+The repositories are not changed. Use --output to print the report as text, json or yaml.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			baseDir := config.Properties.Git.BaseDir
+			if baseDir == "" {
+				baseDir = "./git_repos"
+			}
+
+			if err := validateBaseDir(baseDir); err != nil {
+				return err
+			}
+
+			repositories, err := git.FindRepositoriesRecursive(baseDir, config.Properties.Git.MaxDepth, config.Properties.Git.IncludeSubmoduleRepos)
+			if err != nil {
+				return err
+			}
+
+			statuses := make([]repoStatus, 0, len(repositories))
+			for _, repo := range repositories {
+				statuses = append(statuses, getRepoStatus(repo))
+			}
+
+			return printRepoStatuses(os.Stdout, statuses, config.Properties.Output.Format)
+		},
+	}
+)
+
+// init initializes the status command
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
+
+// getRepoStatus collects the state of the repository with read-only git commands
+func getRepoStatus(repo git.Repository) repoStatus {
+	status := repoStatus{
+		Repository: repo.Name,
+		Path:       repo.Path,
+		Branch:     repo.CurrentBranch,
+		Detached:   git.IsDetachedHead(repo.Path),
+		Dirty:      git.HasUncommittedChanges(repo.Path),
+		Tags:       repo.Tags,
+	}
+
+	staleBranches, err := git.GetStaleTrackingBranches(repo.Path)
+	if err != nil {
+		common.Logger("warning", "Could not check stale tracking branches. repository=%s error=%v", repo.Name, err)
+	}
+	status.StaleBranches = staleBranches
+
+	if status.Detached {
+		status.Branch = ""
+		return status
+	}
+
+	behind, err := git.CountCommitsBehind(repo.Path)
+	if err == nil {
+		status.HasUpstream = true
+		status.Behind = behind
+	}
+	return status
+}
+
+// printRepoStatuses writes the statuses in the output format: a table for "text", or a json, yaml or csv document
+func printRepoStatuses(w io.Writer, statuses []repoStatus, format string) error {
+	switch format {
+	case "json", "yaml":
+		return encodeDocument(w, statuses, format)
+	case "csv":
+		records := [][]string{{"repository", "path", "branch", "detached", "dirty", "has_upstream", "behind", "tags", "stale_branches"}}
+		for _, status := range statuses {
+			records = append(records, []string{
+				status.Repository,
+				status.Path,
+				status.Branch,
+				strconv.FormatBool(status.Detached),
+				strconv.FormatBool(status.Dirty),
+				strconv.FormatBool(status.HasUpstream),
+				strconv.Itoa(status.Behind),
+				strings.Join(status.Tags, " "),
+				strings.Join(status.StaleBranches, " "),
+			})
+		}
+		return csv.NewWriter(w).WriteAll(records)
+	}
+
+	if len(statuses) == 0 {
+		fmt.Fprintln(w, "No repositories found")
+		return nil
+	}
+
+	fmt.Fprintf(w, "%-30s %-20s %-6s %-6s %-6s %s\n", "REPOSITORY", "BRANCH", "DIRTY", "BEHIND", "STALE", "TAGS")
+	staleTotal := 0
+	for _, status := range statuses {
+		branch := status.Branch
+		if status.Detached {
+			branch = "(detached HEAD)"
+		}
+		dirty := "no"
+		if status.Dirty {
+			dirty = "yes"
+		}
+		behind := "-"
+		if status.HasUpstream {
+			behind = strconv.Itoa(status.Behind)
+		}
+		staleTotal += len(status.StaleBranches)
+		fmt.Fprintf(w, "%-30s %-20s %-6s %-6s %-6d %s\n", status.Repository, branch, dirty, behind, len(status.StaleBranches), formatTags(status.Tags))
+	}
+
+	if staleTotal > 0 {
+		fmt.Fprintf(w, "\nStale tracking branches (deleted from the remote): %d\n", staleTotal)
+		for _, status := range statuses {
+			if len(status.StaleBranches) > 0 {
+				fmt.Fprintf(w, "  %s: %s\n", status.Repository, strings.Join(status.StaleBranches, ", "))
+			}
+		}
+		fmt.Fprintln(w, "Run 'updateGit prune' to delete them.")
+	}
+	return nil
+}
+
+// formatTags joins the tags for a text table, "-" when there are none
+func formatTags(tags []string) string {
+	if len(tags) == 0 {
+		return "-"
+	}
+	return strings.Join(tags, ",")
+}
+
+// encodeDocument writes value as an indented json document or as a yaml document
+func encodeDocument(w io.Writer, value any, format string) error {
+	if format == "yaml" {
+		encoder := yaml.NewEncoder(w)
+		defer encoder.Close()
+		return encoder.Encode(value)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/aeciopires/updateGit/internal/git"
+	"github.com/aeciopires/updateGit/internal/gittest"
+)
+
+func TestGetRepoStatus(t *testing.T) {
+	resetProperties(t)
+
+	bareRepo, seedRepo := gittest.NewRemote(t)
+	baseDir := t.TempDir()
+	gittest.Run(t, seedRepo, "tag", "v1.0.0")
+	gittest.Run(t, seedRepo, "push", "--tags")
+
+	behindRepo := gittest.Clone(t, bareRepo, baseDir, "behind")
+	detachedRepo := gittest.Clone(t, bareRepo, baseDir, "detached")
+	gittest.Run(t, detachedRepo, "checkout", "--detach", "HEAD")
+	localRepo := filepath.Join(baseDir, "local")
+	if err := os.Mkdir(localRepo, config.PermissionDir); err != nil {
+		t.Fatalf("could not create directory: %v", err)
+	}
+	gittest.InitRepository(t, localRepo)
+	if err := os.WriteFile(filepath.Join(localRepo, "notes.txt"), []byte("draft"), config.PermissionFile); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+
+	// The branch feature of the clone is stale after the remote branch is deleted
+	gittest.Run(t, seedRepo, "push", "origin", "HEAD:feature")
+	gittest.Run(t, behindRepo, "fetch")
+	gittest.Run(t, behindRepo, "branch", "--track", "feature", "origin/feature")
+	gittest.Run(t, seedRepo, "push", "origin", "--delete", "feature")
+
+	// The clone is behind after a fetch of the new remote commit
+	gittest.CommitFile(t, seedRepo, "README.md", "second", "second commit")
+	gittest.Run(t, seedRepo, "push", "origin", "HEAD:main")
+	gittest.Run(t, behindRepo, "fetch", "--prune")
+
+	repositories, err := git.FindRepositoriesRecursive(baseDir, 1, false)
+	if err != nil {
+		t.Fatalf("FindRepositoriesRecursive failed: %v", err)
+	}
+
+	statuses := make(map[string]repoStatus)
+	for _, repo := range repositories {
+		statuses[repo.Name] = getRepoStatus(repo)
+	}
+
+	expected := map[string]repoStatus{
+		"behind":   {Branch: "main", HasUpstream: true, Behind: 1, Tags: []string{"v1.0.0"}, StaleBranches: []string{"feature"}},
+		"detached": {Detached: true, Tags: []string{"v1.0.0"}},
+		"local":    {Branch: "main", Dirty: true, Tags: []string{}},
+	}
+	for name, want := range expected {
+		got, ok := statuses[name]
+		if !ok {
+			t.Fatalf("repository %s not found in %v", name, statuses)
+		}
+		want.Repository, want.Path = got.Repository, got.Path
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("unexpected status of %s: got %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestPrintRepoStatuses(t *testing.T) {
+	statuses := []repoStatus{
+		{Repository: "api", Branch: "main", Dirty: true, HasUpstream: true, Behind: 2, Tags: []string{"v1.0.0", "stable"}, StaleBranches: []string{"feature", "fix"}},
+		{Repository: "web", Detached: true},
+	}
+
+	var table bytes.Buffer
+	if err := printRepoStatuses(&table, statuses, "text"); err != nil {
+		t.Fatalf("printRepoStatuses failed: %v", err)
+	}
+	for _, text := range []string{"REPOSITORY", "api", "yes", "2", "v1.0.0,stable", "(detached HEAD)",
+		"Stale tracking branches (deleted from the remote): 2", "api: feature, fix", "Run 'updateGit prune'"} {
+		if !strings.Contains(table.String(), text) {
+			t.Errorf("expected %q in table output:\n%s", text, table.String())
+		}
+	}
+
+	var document bytes.Buffer
+	if err := printRepoStatuses(&document, statuses, "json"); err != nil {
+		t.Fatalf("printRepoStatuses failed: %v", err)
+	}
+	var decoded []repoStatus
+	if err := json.Unmarshal(document.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid json output: %v\n%s", err, document.String())
+	}
+	if len(decoded) != 2 || !reflect.DeepEqual(decoded[0], statuses[0]) {
+		t.Errorf("unexpected json output: %+v", decoded)
+	}
+
+	var yamlDocument bytes.Buffer
+	if err := printRepoStatuses(&yamlDocument, statuses, "yaml"); err != nil {
+		t.Fatalf("printRepoStatuses failed: %v", err)
+	}
+	if !strings.Contains(yamlDocument.String(), "repository: web") {
+		t.Errorf("unexpected yaml output:\n%s", yamlDocument.String())
+	}
+}
@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"net/http"
+	"os"
+	"time"
 
 	"github.com/aeciopires/updateGit/internal/config"
 	"github.com/aeciopires/updateGit/internal/update"
@@ -13,23 +16,53 @@ import (
 var (
 	githubRepo string = "aeciopires/updateGit"
 
+	// updateCheckCacheTTL is the time the result of the last release check is reused
+	updateCheckCacheTTL time.Duration
+
+	// listReleases shows the published releases instead of updating
+	listReleases bool
+
+	// updateDryRun shows the binary that would be downloaded instead of updating
+	updateDryRun bool
+
 	// updateCmd represents the update command
 	updateCmd = &cobra.Command{
 		Use:   "update",
 		Short: "Check for a new version and update the application.",
 		Long: `Checks for the latest release on GitHub. If a newer version is found
 for your operating system and architecture, it downloads and replaces the
-current application binary.`,
+current application binary.
+
+Use --list-releases to show the published releases and a summary of their
+release notes without updating.
+
+Use --dry-run to show the download URL and the expected checksum of the new
+binary without downloading it. The exit code is 0 if no update is available
+and 1 if an update is available.`,
 		Run: func(cmd *cobra.Command, args []string) {
+			if listReleases {
+				printReleases()
+				return
+			}
+
 			common.Logger("info", "Checking for updates...")
 
-			release := update.CheckForUpdate(githubRepo)
+			release := update.CheckForUpdateCached(http.DefaultClient, githubRepo, updateCheckCacheTTL)
 
 			if release == nil {
 				common.Logger("warning", "You are already on the latest version: %s\n", config.CLIVersion)
 				return
 			}
 
+			if updateDryRun {
+				printUpdatePlan(release)
+				// Exit code 1 allows scripts to detect an available update
+				os.Exit(1)
+			}
+
+			if notes := update.ReleaseNotesSummary(release); notes != "" {
+				common.Logger("info", "Release notes of %s: %s", release.TagName, notes)
+			}
 			common.Logger("info", "A new version is available: %s. Do you want to update? (y/n): ", release.TagName)
 			var response string
 			fmt.Scanln(&response)
@@ -49,4 +82,45 @@ current application binary.`,
 func init() {
 	rootCmd.AddCommand(updateCmd) // Add update to parent root command
 	// Add flags to the update command if needed
+	updateCmd.Flags().DurationVar(&updateCheckCacheTTL, "update-check-cache-ttl", time.Hour, "Reuse the last release check if it is younger than this duration (0 disables the cache)")
+	updateCmd.Flags().BoolVar(&listReleases, "list-releases", false, "List the published releases and exit")
+	updateCmd.Flags().BoolVar(&updateDryRun, "dry-run", false, "Show the download URL and checksum of the new version without updating (exit code 1 if an update is available)")
+}
+
+// printUpdatePlan shows the binary that would be downloaded to update to release
+func printUpdatePlan(release *update.GitHubRelease) {
+	plan, err := update.PlanUpdate(release)
+	if err != nil {
+		common.Logger("fatal", "%v", err)
+	}
+
+	fmt.Printf("Version:           %s\n", plan.Version)
+	fmt.Printf("Asset:             %s\n", plan.AssetName)
+	fmt.Printf("Download URL:      %s\n", plan.DownloadURL)
+	fmt.Printf("Expected checksum: %s\n", plan.ExpectedChecksum)
+}
+
+// printReleases shows the tag, publication date, release type and release notes summary of the published releases
+func printReleases() {
+	releases, err := update.ListReleases(http.DefaultClient, githubRepo)
+	if err != nil {
+		common.Logger("fatal", "%v", err)
+	}
+
+	for _, release := range releases {
+		releaseType := "stable"
+		if release.Prerelease {
+			releaseType = "pre-release"
+		}
+
+		current := ""
+		if release.TagName == config.CLIVersion {
+			current = " (current)"
+		}
+
+		fmt.Printf("%-12s %-12s %s%s\n", release.TagName, release.PublishedAt.Format("2006-01-02"), releaseType, current)
+		if notes := update.ReleaseNotesSummary(&release); notes != "" {
+			fmt.Printf("    %s\n", notes)
+		}
+	}
 }
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/aeciopires/updateGit/internal/common"
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/aeciopires/updateGit/internal/git"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// watchPull runs pull after each interval until ctx is done. A pull with failed repositories
+// does not stop the loop. With reloadConfig, the config file is read again when it changes,
+// between two pulls
+func watchPull(ctx context.Context, interval time.Duration, reloadConfig bool, pull func(context.Context) error) error {
+	var configChanges <-chan struct{}
+	if reloadConfig {
+		changes, stopWatcher, err := watchConfigFile(config.Properties.DefaultConfigFile)
+		if err != nil {
+			return err
+		}
+		defer stopWatcher()
+		configChanges = changes
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	common.Logger("info", "Watch mode started. interval=%v config_file_watch=%t", interval, reloadConfig)
+	for {
+		err := pull(ctx)
+		var updateErr *git.UpdateError
+		if err != nil && !errors.As(err, &updateErr) {
+			return err
+		}
+		if updateErr != nil {
+			common.Logger("error", "%v", updateErr)
+		}
+
+		if !waitNextPull(ctx, ticker.C, configChanges) {
+			common.Logger("info", "Watch mode stopped")
+			return nil
+		}
+	}
+}
+
+// waitNextPull waits for the next tick, reloading the config file on each change.
+// It returns false if ctx is done first
+func waitNextPull(ctx context.Context, ticks <-chan time.Time, configChanges <-chan struct{}) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-configChanges:
+			reloadConfig()
+		case <-ticks:
+			return true
+		}
+	}
+}
+
+// reloadConfig loads the config file again, starting from the defaults so a key removed
+// from the file gets its default value back. If the new configuration is not valid,
+// the previous one is kept
+func reloadConfig() {
+	previous := config.Properties
+	commandLine := changedFlags()
+
+	config.Properties = config.Config{}
+	config.SetDefaultConfig()
+	config.Properties.DefaultConfigFile = previous.DefaultConfigFile
+	viper.Reset()
+
+	err := commandLine.apply()
+	if err == nil {
+		err = loadConfig()
+	}
+	if err != nil {
+		config.Properties = previous
+		common.Logger("error", "Could not reload the config file, keeping the previous configuration: %v", err)
+		return
+	}
+	common.Logger("info", "config reloaded. config_file=%s", config.Properties.DefaultConfigFile)
+}
+
+// watchConfigFile sends to the returned channel the changes of configFile, until the returned
+// function is called. The directory is watched, as editors often replace the file instead of writing it
+func watchConfigFile(configFile string) (<-chan struct{}, func(), error) {
+	absConfigFile, err := filepath.Abs(configFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not watch config file %s: %w", configFile, err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not watch config file %s: %w", configFile, err)
+	}
+	if err := watcher.Add(filepath.Dir(absConfigFile)); err != nil {
+		watcher.Close()
+		return nil, nil, fmt.Errorf("could not watch config file %s: %w", configFile, err)
+	}
+
+	// Changes made during a pull are applied once, before the next one
+	changes := make(chan struct{}, 1)
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != absConfigFile || !event.Has(fsnotify.Write|fsnotify.Create) {
+					continue
+				}
+				common.Logger("debug", "Config file changed. event=%v", event)
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				common.Logger("warning", "Error watching config file %s: %v", configFile, err)
+			}
+		}
+	}()
+
+	return changes, func() { watcher.Close() }, nil
+}
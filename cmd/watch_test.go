@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/aeciopires/updateGit/internal/git"
+)
+
+func TestWatchPull(t *testing.T) {
+	resetProperties(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Failed repositories do not stop the loop, which ends when the context is done
+	pulls := 0
+	err := watchPull(ctx, 10*time.Millisecond, false, func(context.Context) error {
+		pulls++
+		if pulls == 3 {
+			cancel()
+		}
+		return &git.UpdateError{Summary: &git.UpdateSummary{Total: 1, Failed: 1}}
+	})
+	if err != nil {
+		t.Fatalf("watchPull returned error: %v", err)
+	}
+	if pulls != 3 {
+		t.Errorf("expected 3 pulls, got %d", pulls)
+	}
+
+	// Other errors stop the loop
+	templateErr := errors.New("could not render the template")
+	err = watchPull(context.Background(), 10*time.Millisecond, false, func(context.Context) error {
+		return templateErr
+	})
+	if !errors.Is(err, templateErr) {
+		t.Errorf("expected the error of the pull, got %v", err)
+	}
+}
+
+func TestWatchPullConfigFileWatch(t *testing.T) {
+	configFile := setupConfigFile(t, "git:\n  max_concurrent: 3\n")
+	if err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var maxConcurrent []int
+	err := watchPull(ctx, 20*time.Millisecond, true, func(context.Context) error {
+		maxConcurrent = append(maxConcurrent, config.Properties.Git.MaxConcurrent)
+		switch {
+		case len(maxConcurrent) == 1:
+			if err := os.WriteFile(configFile, []byte("git:\n  max_concurrent: 5\n"), config.PermissionFile); err != nil {
+				t.Errorf("could not write config file: %v", err)
+			}
+		case config.Properties.Git.MaxConcurrent == 5:
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("watchPull returned error: %v", err)
+	}
+	if got := maxConcurrent[len(maxConcurrent)-1]; maxConcurrent[0] != 3 || got != 5 {
+		t.Errorf("expected max_concurrent 3 in the first pull and 5 after the reload, got %v", maxConcurrent)
+	}
+}
+
+func TestReloadConfigKeepsPreviousOnError(t *testing.T) {
+	configFile := setupConfigFile(t, "git:\n  max_concurrent: 3\n")
+	if err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig returned error: %v", err)
+	}
+
+	if err := os.WriteFile(configFile, []byte("git:\n  max_concurrent: 5\nbackup:\n  strategy: \"tarball\"\n"), config.PermissionFile); err != nil {
+		t.Fatalf("could not write config file: %v", err)
+	}
+	reloadConfig()
+
+	if config.Properties.Git.MaxConcurrent != 3 || config.Properties.Backup.Strategy == "tarball" {
+		t.Errorf("expected the previous configuration, got max_concurrent=%d strategy=%s",
+			config.Properties.Git.MaxConcurrent, config.Properties.Backup.Strategy)
+	}
+}
+
+func TestReloadConfigRemovedKey(t *testing.T) {
+	configFile := setupConfigFile(t, "git:\n  max_concurrent: 3\nbackup:\n  strategy: \"stash\"\n")
+	if err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig returned error: %v", err)
+	}
+
+	if err := os.WriteFile(configFile, []byte("git:\n  max_concurrent: 5\n"), config.PermissionFile); err != nil {
+		t.Fatalf("could not write config file: %v", err)
+	}
+	reloadConfig()
+
+	if config.Properties.Git.MaxConcurrent != 5 {
+		t.Errorf("expected max_concurrent 5 after the reload, got %d", config.Properties.Git.MaxConcurrent)
+	}
+	if config.Properties.Backup.Strategy != "copy" {
+		t.Errorf("expected the default strategy 'copy' once removed from the file, got %s", config.Properties.Backup.Strategy)
+	}
+	if config.Properties.DefaultConfigFile != configFile {
+		t.Errorf("expected the reloaded config file %s, got %s", configFile, config.Properties.DefaultConfigFile)
+	}
+}
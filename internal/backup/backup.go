@@ -4,15 +4,19 @@
 package backup
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/aeciopires/updateGit/internal/common"
 	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/aeciopires/updateGit/internal/metrics"
 )
 
 // BackupStrategy represents different backup approaches
@@ -21,6 +25,10 @@ type BackupStrategy string
 const (
 	StrategyStash BackupStrategy = "stash"
 	StrategyCopy  BackupStrategy = "copy"
+	// StrategyAuto uses StrategyStash for repositories with uncommitted changes and StrategyCopy for the others
+	StrategyAuto BackupStrategy = "auto"
+	// StrategyBundle saves all refs of the repository in a single file created by git bundle
+	StrategyBundle BackupStrategy = "bundle"
 )
 
 // BackupManager handles repository backups
@@ -28,6 +36,38 @@ type BackupManager struct {
 	BackupDir string
 	Strategy  BackupStrategy
 	Timestamp string
+	// ExcludeGitDir skips the .git directory in copy backups.
+	// With false the backup is a standalone clone, including the whole history.
+	ExcludeGitDir bool
+	// StashDropOnRestore drops the stash entry after a stash backup is restored
+	StashDropOnRestore bool
+
+	progressCallback func(event BackupProgressEvent)
+}
+
+// BackupProgressEvent reports the progress of a copy backup after each copied file
+type BackupProgressEvent struct {
+	RepoName       string
+	BytesCopied    int64
+	TotalBytes     int64
+	FilesProcessed int
+	TotalFiles     int
+}
+
+// copyProgress keeps the progress of one copy backup
+type copyProgress struct {
+	event    BackupProgressEvent
+	callback func(event BackupProgressEvent)
+}
+
+// fileCopied updates the progress with a copied file and calls the callback
+func (p *copyProgress) fileCopied(size int64) {
+	if p == nil || p.callback == nil {
+		return
+	}
+	p.event.BytesCopied += size
+	p.event.FilesProcessed++
+	p.callback(p.event)
 }
 
 // BackupInfo contains information about a backup
@@ -50,42 +90,91 @@ func (e *BackupError) Error() string {
 	return fmt.Sprintf("backup %s failed for repository '%s': %v", e.Operation, e.Repository, e.Err)
 }
 
-// NewBackupManager creates a new backup manager
-func NewBackupManager(backupDir string, strategy BackupStrategy) *BackupManager {
-	timestamp := time.Now().Format("20060102-150405")
+// Unwrap returns the cause of the error, so errors.Is detects e.g. a cancelled context
+func (e *BackupError) Unwrap() error {
+	return e.Err
+}
+
+// backupTimestampLayout is the layout of the names of the timestamped backup directories
+const backupTimestampLayout = "20060102-150405"
+
+// IsValid reports whether the strategy is one of the strategies supported by BackupManager
+func (s BackupStrategy) IsValid() bool {
+	switch s {
+	case StrategyStash, StrategyCopy, StrategyAuto, StrategyBundle:
+		return true
+	}
+	return false
+}
+
+// NewBackupManager creates a new backup manager and its timestamped backup directory.
+// An empty strategy means StrategyCopy, other unknown strategies return an error.
+func NewBackupManager(backupDir string, strategy BackupStrategy) (*BackupManager, error) {
+	timestamp := time.Now().Format(backupTimestampLayout)
 
 	if backupDir == "" {
 		backupDir = "./backups"
 	}
+	if strategy == "" {
+		strategy = StrategyCopy
+	}
+	if !strategy.IsValid() {
+		return nil, fmt.Errorf("unknown backup strategy '%s'", strategy)
+	}
 
 	fullBackupDir := filepath.Join(backupDir, timestamp)
 	if err := os.MkdirAll(fullBackupDir, config.PermissionDir); err != nil {
-		common.Logger("fatal", "Failed to create backup directory. error=%v", err)
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
 	manager := &BackupManager{
-		BackupDir: fullBackupDir,
-		Strategy:  strategy,
-		Timestamp: timestamp,
+		BackupDir:     fullBackupDir,
+		Strategy:      strategy,
+		Timestamp:     timestamp,
+		ExcludeGitDir: true,
 	}
 
 	common.Logger("info", "Backup manager initialized. backup_dir=%s strategy=%s timestamp=%s", fullBackupDir, strategy, timestamp)
 
-	return manager
+	return manager, nil
+}
+
+// SetProgressCallback sets the function called after each file copied by a copy backup.
+// A nil function disables the progress reporting.
+func (bm *BackupManager) SetProgressCallback(fn func(event BackupProgressEvent)) {
+	bm.progressCallback = fn
 }
 
-// CreateBackup creates a backup of the specified repository
-func (bm *BackupManager) CreateBackup(repoPath, repoName string) (*BackupInfo, error) {
+// CreateBackup creates a backup of the specified repository.
+// A copy backup is aborted when ctx is cancelled, leaving the files copied so far.
+func (bm *BackupManager) CreateBackup(ctx context.Context, repoPath, repoName string) (*BackupInfo, error) {
 	common.Logger("info", "Creating repository backup. repository=%s path=%s strategy=%s", repoName, repoPath, bm.Strategy)
 
+	var info *BackupInfo
+	var err error
 	switch bm.Strategy {
+	case StrategyAuto:
+		if bm.hasUncommittedChanges(repoPath) {
+			common.Logger("debug", "Uncommitted changes found, using stash backup. repository=%s", repoName)
+			info, err = bm.createStashBackup(repoPath, repoName)
+		} else {
+			common.Logger("debug", "No uncommitted changes, using copy backup. repository=%s", repoName)
+			info, err = bm.createCopyBackup(ctx, repoPath, repoName)
+		}
 	case StrategyStash:
-		return bm.createStashBackup(repoPath, repoName)
+		info, err = bm.createStashBackup(repoPath, repoName)
 	case StrategyCopy:
-		return bm.createCopyBackup(repoPath, repoName)
+		info, err = bm.createCopyBackup(ctx, repoPath, repoName)
+	case StrategyBundle:
+		info, err = bm.createBundleBackup(ctx, repoPath, repoName)
 	default:
-		return bm.createCopyBackup(repoPath, repoName)
+		err = &BackupError{Repository: repoName, Operation: "create", Err: fmt.Errorf("unknown backup strategy '%s'", bm.Strategy)}
+	}
+
+	if err == nil {
+		metrics.BackupCreated.Inc()
 	}
+	return info, err
 }
 
 // createStashBackup creates a git stash backup
@@ -111,7 +200,7 @@ func (bm *BackupManager) createStashBackup(repoPath, repoName string) (*BackupIn
 
 	return &BackupInfo{
 		Repository:   repoName,
-		BackupPath:   fmt.Sprintf("stash: %s", stashMessage),
+		BackupPath:   stashBackupPrefix + stashMessage,
 		Strategy:     StrategyStash,
 		Timestamp:    time.Now(),
 		OriginalPath: repoPath,
@@ -119,7 +208,7 @@ func (bm *BackupManager) createStashBackup(repoPath, repoName string) (*BackupIn
 }
 
 // createCopyBackup creates a file system copy backup
-func (bm *BackupManager) createCopyBackup(repoPath, repoName string) (*BackupInfo, error) {
+func (bm *BackupManager) createCopyBackup(ctx context.Context, repoPath, repoName string) (*BackupInfo, error) {
 	backupPath := filepath.Join(bm.BackupDir, repoName)
 	common.Logger("debug", "Attempting copy backup. repo_name='%s', backup_path='%s'", repoName, backupPath)
 
@@ -127,7 +216,7 @@ func (bm *BackupManager) createCopyBackup(repoPath, repoName string) (*BackupInf
 		return nil, &BackupError{Repository: repoName, Operation: "create directory", Err: err}
 	}
 
-	if err := bm.copyRepository(repoPath, backupPath); err != nil {
+	if err := bm.copyRepository(ctx, repoName, repoPath, backupPath); err != nil {
 		return nil, &BackupError{Repository: repoName, Operation: "copy files", Err: err}
 	}
 
@@ -142,10 +231,51 @@ func (bm *BackupManager) createCopyBackup(repoPath, repoName string) (*BackupInf
 	}, nil
 }
 
-// copyRepository copies the repository files to the backup directory
-func (bm *BackupManager) copyRepository(src, dst string) error {
+// createBundleBackup saves the branches and tags of the repository in <BackupDir>/<repoName>.bundle.
+// Uncommitted changes are not part of a bundle.
+func (bm *BackupManager) createBundleBackup(ctx context.Context, repoPath, repoName string) (*BackupInfo, error) {
+	backupPath := filepath.Join(bm.BackupDir, repoName+".bundle")
+	common.Logger("debug", "Attempting bundle backup. repo_name='%s', backup_path='%s'", repoName, backupPath)
+
+	// Names of repositories in subdirectories of the base directory have slashes, e.g. "work/api"
+	if err := os.MkdirAll(filepath.Dir(backupPath), config.PermissionDir); err != nil {
+		return nil, &BackupError{Repository: repoName, Operation: "create directory", Err: err}
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "bundle", "create", backupPath, "--all")
+	cmd.Dir = repoPath
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, &BackupError{Repository: repoName, Operation: "git bundle", Err: fmt.Errorf("%v: %s", err, string(out))}
+	}
+	common.Logger("info", "Git bundle backup created. repository=%s backup_path=%s", repoName, backupPath)
+
+	return &BackupInfo{
+		Repository:   repoName,
+		BackupPath:   backupPath,
+		Strategy:     StrategyBundle,
+		Timestamp:    time.Now(),
+		OriginalPath: repoPath,
+	}, nil
+}
+
+// copyRepository copies the repository files to the backup directory. The walk stops when ctx is cancelled
+func (bm *BackupManager) copyRepository(ctx context.Context, repoName, src, dst string) error {
+	progress, err := bm.newCopyProgress(repoName, src)
+	if err != nil {
+		return err
+	}
+
+	return bm.copyTree(ctx, src, dst, bm.ExcludeGitDir, progress)
+}
+
+// copyTree copies the files, directories and symlinks of src to dst, overwriting existing files.
+// The .git directory is skipped when excludeGitDir is set. The walk stops when ctx is cancelled
+func (bm *BackupManager) copyTree(ctx context.Context, src, dst string, excludeGitDir bool, progress *copyProgress) error {
 	common.Logger("debug", "Starting repository copy walk. src='%s'", src)
 	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		if err != nil {
 			common.Logger("error", "Error accessing path '%s' during walk: %v", path, err)
 			return err
@@ -158,7 +288,7 @@ func (bm *BackupManager) copyRepository(src, dst string) error {
 		}
 		dstPath := filepath.Join(dst, relPath)
 
-		if info.IsDir() && info.Name() == ".git" {
+		if excludeGitDir && info.IsDir() && info.Name() == ".git" {
 			common.Logger("debug", "Skipping .git directory: '%s'", path)
 			return filepath.SkipDir
 		}
@@ -178,7 +308,7 @@ func (bm *BackupManager) copyRepository(src, dst string) error {
 		}
 
 		common.Logger("debug", "Attempting to copy file: '%s' -> '%s'", path, dstPath)
-		return bm.copyFile(path, dstPath)
+		return bm.copyFile(path, dstPath, progress)
 	})
 
 	if err != nil {
@@ -189,8 +319,41 @@ func (bm *BackupManager) copyRepository(src, dst string) error {
 	return err
 }
 
-// copyFile copies a single file from source to destination
-func (bm *BackupManager) copyFile(src, dst string) error {
+// newCopyProgress counts the files and bytes copied by copyRepository.
+// The totals are only calculated when a progress callback is set.
+func (bm *BackupManager) newCopyProgress(repoName, src string) (*copyProgress, error) {
+	if bm.progressCallback == nil {
+		return nil, nil
+	}
+
+	progress := &copyProgress{
+		event:    BackupProgressEvent{RepoName: repoName},
+		callback: bm.progressCallback,
+	}
+
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if bm.ExcludeGitDir && info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if info.Mode().IsRegular() {
+			progress.event.TotalFiles++
+			progress.event.TotalBytes += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		common.Logger("error", "Could not calculate the size of '%s': %v", src, err)
+		return nil, err
+	}
+
+	return progress, nil
+}
+
+// copyFile copies a single file from source to destination and reports it to progress
+func (bm *BackupManager) copyFile(src, dst string, progress *copyProgress) error {
 	if err := os.MkdirAll(filepath.Dir(dst), config.PermissionDir); err != nil {
 		common.Logger("error", "copyFile: Failed to create parent dir for '%s': %v", dst, err)
 		return err
@@ -223,7 +386,12 @@ func (bm *BackupManager) copyFile(src, dst string) error {
 	}
 
 	common.Logger("debug", "Successfully copied %d bytes for file: %s", bytesCopied, dst)
-	return os.Chmod(dst, srcInfo.Mode())
+	if err := os.Chmod(dst, srcInfo.Mode()); err != nil {
+		return err
+	}
+
+	progress.fileCopied(bytesCopied)
+	return nil
 }
 
 // hasUncommittedChanges checks if there are uncommitted changes in the repository
@@ -240,22 +408,158 @@ func (bm *BackupManager) hasUncommittedChanges(repoPath string) bool {
 
 // RestoreBackup restores a backup for a repository
 func (bm *BackupManager) RestoreBackup(backupInfo *BackupInfo) error {
-	common.Logger("info", "Restore functionality not yet implemented. repository=%s backup_path=%s strategy=%s",
+	common.Logger("info", "Restoring repository backup. repository=%s backup_path=%s strategy=%s",
 		backupInfo.Repository, backupInfo.BackupPath, backupInfo.Strategy)
-	return fmt.Errorf("restore functionality not yet implemented")
+
+	switch backupInfo.Strategy {
+	case StrategyStash:
+		return bm.restoreStashBackup(backupInfo)
+	case StrategyCopy:
+		return bm.restoreCopyBackup(backupInfo)
+	case StrategyBundle:
+		return bm.restoreBundleBackup(backupInfo)
+	default:
+		return &BackupError{Repository: backupInfo.Repository, Operation: "restore", Err: fmt.Errorf("unknown backup strategy '%s'", backupInfo.Strategy)}
+	}
+}
+
+// restoreCopyBackup copies the files of a copy backup back to the repository, overwriting the existing files.
+// Files created after the backup are kept. The backup is validated before the repository is touched.
+func (bm *BackupManager) restoreCopyBackup(backupInfo *BackupInfo) error {
+	info, err := os.Stat(backupInfo.BackupPath)
+	if err != nil {
+		return &BackupError{Repository: backupInfo.Repository, Operation: "restore", Err: err}
+	}
+	if !info.IsDir() {
+		return &BackupError{Repository: backupInfo.Repository, Operation: "restore", Err: fmt.Errorf("backup path is not a directory: %s", backupInfo.BackupPath)}
+	}
+
+	if err := bm.copyTree(context.Background(), backupInfo.BackupPath, backupInfo.OriginalPath, false, nil); err != nil {
+		return &BackupError{Repository: backupInfo.Repository, Operation: "restore", Err: err}
+	}
+	common.Logger("info", "Copy backup restored. repository=%s backup_path=%s path=%s", backupInfo.Repository, backupInfo.BackupPath, backupInfo.OriginalPath)
+	return nil
 }
 
-// CleanupOldBackups removes backups older than the specified number of days
+// restoreBundleBackup clones the bundle to the original path of the repository, which must not exist or be empty.
+// The origin remote of the restored clone is the bundle file.
+func (bm *BackupManager) restoreBundleBackup(backupInfo *BackupInfo) error {
+	if _, err := os.Stat(backupInfo.BackupPath); err != nil {
+		return &BackupError{Repository: backupInfo.Repository, Operation: "restore", Err: err}
+	}
+	if entries, err := os.ReadDir(backupInfo.OriginalPath); err == nil && len(entries) > 0 {
+		return &BackupError{Repository: backupInfo.Repository, Operation: "restore", Err: fmt.Errorf("repository path is not empty: %s", backupInfo.OriginalPath)}
+	}
+
+	cmd := exec.Command("git", "clone", backupInfo.BackupPath, backupInfo.OriginalPath)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return &BackupError{Repository: backupInfo.Repository, Operation: "git clone", Err: fmt.Errorf("%v: %s", err, string(out))}
+	}
+	common.Logger("info", "Git bundle backup restored. repository=%s backup_path=%s path=%s", backupInfo.Repository, backupInfo.BackupPath, backupInfo.OriginalPath)
+	return nil
+}
+
+// stashBackupPrefix is the prefix of BackupInfo.BackupPath of stash backups, followed by the stash message
+const stashBackupPrefix = "stash: "
+
+// restoreStashBackup applies the stash entry created by createStashBackup. The entry is found by its message,
+// because the indices change with other stash operations. It is kept unless StashDropOnRestore is set.
+func (bm *BackupManager) restoreStashBackup(backupInfo *BackupInfo) error {
+	stashMessage, found := strings.CutPrefix(backupInfo.BackupPath, stashBackupPrefix)
+	if !found {
+		common.Logger("info", "Stash backup has no changes to restore. repository=%s", backupInfo.Repository)
+		return nil
+	}
+
+	stashRef, err := findStashByMessage(backupInfo.OriginalPath, stashMessage)
+	if err != nil {
+		return &BackupError{Repository: backupInfo.Repository, Operation: "restore", Err: err}
+	}
+
+	cmd := exec.Command("git", "stash", "apply", stashRef)
+	cmd.Dir = backupInfo.OriginalPath
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return &BackupError{Repository: backupInfo.Repository, Operation: "git stash apply", Err: fmt.Errorf("%v: %s", err, string(out))}
+	}
+	common.Logger("info", "Git stash backup restored. repository=%s stash=%s message=%s", backupInfo.Repository, stashRef, stashMessage)
+
+	if !bm.StashDropOnRestore {
+		return nil
+	}
+
+	cmd = exec.Command("git", "stash", "drop", stashRef)
+	cmd.Dir = backupInfo.OriginalPath
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return &BackupError{Repository: backupInfo.Repository, Operation: "git stash drop", Err: fmt.Errorf("%v: %s", err, string(out))}
+	}
+	common.Logger("info", "Git stash backup dropped. repository=%s stash=%s", backupInfo.Repository, stashRef)
+	return nil
+}
+
+// findStashByMessage returns the reference (e.g. stash@{2}) of the stash entry with the message.
+// git stash push -m adds the branch to the subject ("On main: <message>"), so the message may also be a suffix.
+func findStashByMessage(repoPath, message string) (string, error) {
+	cmd := exec.Command("git", "stash", "list", "--format=%gd:%s")
+	cmd.Dir = repoPath
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git stash list: %v", err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		ref, subject, found := strings.Cut(line, ":")
+		if found && (subject == message || strings.HasSuffix(subject, ": "+message)) {
+			return ref, nil
+		}
+	}
+	return "", fmt.Errorf("stash entry with message '%s' not found", message)
+}
+
+// CleanupOldBackups removes backups older than the specified number of days.
+// The timestamped directories next to BackupDir are checked by their names, other directories are ignored.
+// All old backups are tried and the errors of the ones that could not be removed are joined.
 func (bm *BackupManager) CleanupOldBackups(days int) error {
-	common.Logger("info", "Backup cleanup not yet implemented. retention_days=%d", days)
-	return fmt.Errorf("cleanup functionality not yet implemented")
+	if days <= 0 {
+		return fmt.Errorf("invalid retention of %d days", days)
+	}
+
+	parentDir := filepath.Dir(bm.BackupDir)
+	entries, err := os.ReadDir(parentDir)
+	if err != nil {
+		return &BackupError{Repository: "*", Operation: "cleanup", Err: err}
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	var errs []error
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == bm.Timestamp {
+			continue
+		}
+		timestamp, err := time.ParseInLocation(backupTimestampLayout, entry.Name(), time.Local)
+		if err != nil || !timestamp.Before(cutoff) {
+			continue
+		}
+
+		path := filepath.Join(parentDir, entry.Name())
+		if err := os.RemoveAll(path); err != nil {
+			errs = append(errs, &BackupError{Repository: "*", Operation: "cleanup", Err: err})
+			continue
+		}
+		removed++
+		common.Logger("debug", "Old backup removed. path=%s", path)
+	}
+
+	common.Logger("info", "Old backups cleanup completed. backup_dir=%s retention_days=%d removed=%d failed=%d", parentDir, days, removed, len(errs))
+	return errors.Join(errs...)
 }
 
 // GetBackupStats returns statistics about the backup manager
 func (bm *BackupManager) GetBackupStats() map[string]interface{} {
 	return map[string]interface{}{
-		"backup_dir": bm.BackupDir,
-		"strategy":   bm.Strategy,
-		"timestamp":  bm.Timestamp,
+		"backup_dir":      bm.BackupDir,
+		"strategy":        bm.Strategy,
+		"timestamp":       bm.Timestamp,
+		"exclude_git_dir": bm.ExcludeGitDir,
 	}
 }
\ No newline at end of file
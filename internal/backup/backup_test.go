@@ -0,0 +1,356 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/aeciopires/updateGit/internal/gittest"
+)
+
+// writeTestFile creates a file (and its parent directories) with the given content
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), config.PermissionDir); err != nil {
+		t.Fatalf("could not create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), config.PermissionFile); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+}
+
+func TestCreateCopyBackup(t *testing.T) {
+	srcDir, err := os.MkdirTemp("", "updateGit-src-")
+	if err != nil {
+		t.Fatalf("could not create source directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(srcDir) })
+
+	dstDir, err := os.MkdirTemp("", "updateGit-dst-")
+	if err != nil {
+		t.Fatalf("could not create destination directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dstDir) })
+
+	files := map[string]string{
+		"README.md":          "# project",
+		"main.go":            "package main",
+		"internal/pkg/a.txt": "nested content",
+	}
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(srcDir, name), content)
+	}
+	writeTestFile(t, filepath.Join(srcDir, ".git", "HEAD"), "ref: refs/heads/main")
+
+	bm := &BackupManager{BackupDir: dstDir, Strategy: StrategyCopy, ExcludeGitDir: true}
+	info, err := bm.createCopyBackup(context.Background(), srcDir, "project")
+	if err != nil {
+		t.Fatalf("createCopyBackup returned error: %v", err)
+	}
+
+	expectedBackupPath := filepath.Join(dstDir, "project")
+	if info.BackupPath != expectedBackupPath {
+		t.Errorf("expected backup path '%s', got '%s'", expectedBackupPath, info.BackupPath)
+	}
+	if info.Strategy != StrategyCopy || info.OriginalPath != srcDir {
+		t.Errorf("unexpected backup info: %+v", info)
+	}
+
+	for name, content := range files {
+		data, err := os.ReadFile(filepath.Join(expectedBackupPath, name))
+		if err != nil {
+			t.Errorf("file %s was not copied: %v", name, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("file %s has content %q, expected %q", name, data, content)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(expectedBackupPath, ".git")); !os.IsNotExist(err) {
+		t.Errorf("expected .git directory to be skipped, stat error: %v", err)
+	}
+}
+
+func TestSetProgressCallback(t *testing.T) {
+	srcDir := t.TempDir()
+	dstDir := t.TempDir()
+
+	files := map[string]string{
+		"a.txt":         "12345",
+		"b.txt":         "1234567890",
+		"dir/c.txt":     "123",
+		".git/HEAD":     "ref: refs/heads/main",
+		".git/config":   "[core]",
+		"dir/empty.txt": "",
+	}
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(srcDir, name), content)
+	}
+
+	var events []BackupProgressEvent
+	bm := &BackupManager{BackupDir: dstDir, Strategy: StrategyCopy, ExcludeGitDir: true}
+	bm.SetProgressCallback(func(event BackupProgressEvent) {
+		events = append(events, event)
+	})
+
+	if _, err := bm.createCopyBackup(context.Background(), srcDir, "project"); err != nil {
+		t.Fatalf("createCopyBackup returned error: %v", err)
+	}
+
+	// .git files are not copied, so they are not reported
+	const totalFiles, totalBytes = 4, 18
+	if len(events) != totalFiles {
+		t.Fatalf("expected %d events, got %d: %+v", totalFiles, len(events), events)
+	}
+
+	for i, event := range events {
+		if event.RepoName != "project" || event.TotalFiles != totalFiles || event.TotalBytes != totalBytes {
+			t.Errorf("event %d has unexpected totals: %+v", i, event)
+		}
+		if event.FilesProcessed != i+1 {
+			t.Errorf("event %d reports %d files processed", i, event.FilesProcessed)
+		}
+		if i > 0 && event.BytesCopied < events[i-1].BytesCopied {
+			t.Errorf("bytes copied decreased in event %d: %+v", i, event)
+		}
+	}
+
+	if last := events[len(events)-1]; last.BytesCopied != totalBytes {
+		t.Errorf("expected %d bytes copied at the end, got %d", totalBytes, last.BytesCopied)
+	}
+}
+
+func BenchmarkCopyRepository(b *testing.B) {
+	srcDir := b.TempDir()
+	dstRoot := b.TempDir()
+
+	// Synthetic tree with 1000 files from 512 bytes to 64 KiB spread in 20 directories
+	sizes := []int{512, 2 * 1024, 8 * 1024, 32 * 1024, 64 * 1024}
+	var totalBytes int64
+	for i := 0; i < 1000; i++ {
+		size := sizes[i%len(sizes)]
+		path := filepath.Join(srcDir, fmt.Sprintf("dir-%02d", i%20), fmt.Sprintf("file-%04d.txt", i))
+		if err := os.MkdirAll(filepath.Dir(path), config.PermissionDir); err != nil {
+			b.Fatalf("could not create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), config.PermissionFile); err != nil {
+			b.Fatalf("could not write file: %v", err)
+		}
+		totalBytes += int64(size)
+	}
+
+	bm := &BackupManager{BackupDir: dstRoot, Strategy: StrategyCopy}
+
+	b.SetBytes(totalBytes)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dstDir := filepath.Join(dstRoot, fmt.Sprintf("copy-%d", i))
+		if err := bm.copyRepository(context.Background(), "project", srcDir, dstDir); err != nil {
+			b.Fatalf("copyRepository returned error: %v", err)
+		}
+
+		b.StopTimer()
+		os.RemoveAll(dstDir)
+		b.StartTimer()
+	}
+}
+
+func TestCreateBackupAuto(t *testing.T) {
+	repoDir := t.TempDir()
+	gittest.InitRepository(t, repoDir)
+
+	bm := &BackupManager{BackupDir: t.TempDir(), Strategy: StrategyAuto, Timestamp: "20240101-000000", ExcludeGitDir: true}
+
+	info, err := bm.CreateBackup(context.Background(), repoDir, "project")
+	if err != nil {
+		t.Fatalf("CreateBackup returned error: %v", err)
+	}
+	if info.Strategy != StrategyCopy {
+		t.Errorf("clean repository: expected strategy '%s', got '%s'", StrategyCopy, info.Strategy)
+	}
+
+	writeTestFile(t, filepath.Join(repoDir, "README.md"), "changed")
+
+	info, err = bm.CreateBackup(context.Background(), repoDir, "project")
+	if err != nil {
+		t.Fatalf("CreateBackup returned error: %v", err)
+	}
+	if info.Strategy != StrategyStash {
+		t.Errorf("repository with changes: expected strategy '%s', got '%s'", StrategyStash, info.Strategy)
+	}
+}
+
+func TestCopyRepositoryCancelled(t *testing.T) {
+	srcDir := t.TempDir()
+	writeTestFile(t, filepath.Join(srcDir, "README.md"), "content")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	bm := &BackupManager{BackupDir: t.TempDir(), Strategy: StrategyCopy}
+	_, err := bm.CreateBackup(ctx, srcDir, "project")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(bm.BackupDir, "project", "README.md")); !os.IsNotExist(statErr) {
+		t.Errorf("file copied after the context was cancelled: %v", statErr)
+	}
+}
+
+func TestRestoreStashBackup(t *testing.T) {
+	repoDir := t.TempDir()
+	gittest.InitRepository(t, repoDir)
+
+	bm := &BackupManager{BackupDir: t.TempDir(), Strategy: StrategyStash, Timestamp: "20240101-000000"}
+	writeTestFile(t, filepath.Join(repoDir, "README.md"), "changed")
+	info, err := bm.CreateBackup(context.Background(), repoDir, "project")
+	if err != nil {
+		t.Fatalf("CreateBackup returned error: %v", err)
+	}
+
+	// Another stash entry shifts the index of the backup
+	writeTestFile(t, filepath.Join(repoDir, "other.txt"), "other")
+	gittest.Run(t, repoDir, "stash", "push", "--include-untracked", "-m", "unrelated")
+
+	if err := bm.RestoreBackup(info); err != nil {
+		t.Fatalf("RestoreBackup returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(repoDir, "README.md"))
+	if err != nil || string(data) != "changed" {
+		t.Errorf("expected README.md with the stashed change, got %q (error: %v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, "other.txt")); !os.IsNotExist(err) {
+		t.Errorf("the unrelated stash entry was applied")
+	}
+
+	// The entry is kept by default, so it can be found again
+	if _, err := findStashByMessage(repoDir, "updateGit backup 20240101-000000"); err != nil {
+		t.Errorf("stash entry was dropped: %v", err)
+	}
+}
+
+func TestRestoreCopyBackup(t *testing.T) {
+	repoDir := t.TempDir()
+	writeTestFile(t, filepath.Join(repoDir, "README.md"), "original")
+	writeTestFile(t, filepath.Join(repoDir, "src", "main.go"), "package main")
+
+	bm := &BackupManager{BackupDir: t.TempDir(), Strategy: StrategyCopy, Timestamp: "20240101-000000"}
+	info, err := bm.CreateBackup(context.Background(), repoDir, "project")
+	if err != nil {
+		t.Fatalf("CreateBackup returned error: %v", err)
+	}
+
+	writeTestFile(t, filepath.Join(repoDir, "README.md"), "changed")
+	if err := os.Remove(filepath.Join(repoDir, "src", "main.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bm.RestoreBackup(info); err != nil {
+		t.Fatalf("RestoreBackup returned error: %v", err)
+	}
+
+	for path, want := range map[string]string{"README.md": "original", filepath.Join("src", "main.go"): "package main"} {
+		data, err := os.ReadFile(filepath.Join(repoDir, path))
+		if err != nil || string(data) != want {
+			t.Errorf("expected %s with %q, got %q (error: %v)", path, want, data, err)
+		}
+	}
+
+	// A missing backup must not touch the repository
+	info.BackupPath = filepath.Join(t.TempDir(), "missing")
+	writeTestFile(t, filepath.Join(repoDir, "README.md"), "kept")
+	var backupErr *BackupError
+	if err := bm.RestoreBackup(info); !errors.As(err, &backupErr) || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a BackupError for the missing backup, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(repoDir, "README.md")); string(data) != "kept" {
+		t.Errorf("repository was changed by a failed restore: %q", data)
+	}
+}
+
+func TestCleanupOldBackups(t *testing.T) {
+	parentDir := t.TempDir()
+	now := time.Now()
+	current := now.Format(backupTimestampLayout)
+	old := now.AddDate(0, 0, -10).Format(backupTimestampLayout)
+	recent := now.AddDate(0, 0, -2).Format(backupTimestampLayout)
+
+	for _, name := range []string{current, old, recent, "not-a-backup"} {
+		writeTestFile(t, filepath.Join(parentDir, name, "project", "README.md"), "backup")
+	}
+
+	bm := &BackupManager{BackupDir: filepath.Join(parentDir, current), Strategy: StrategyCopy, Timestamp: current}
+	if err := bm.CleanupOldBackups(7); err != nil {
+		t.Fatalf("CleanupOldBackups returned error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(parentDir, old)); !os.IsNotExist(err) {
+		t.Errorf("expected the backup of 10 days ago to be removed")
+	}
+	for _, name := range []string{current, recent, "not-a-backup"} {
+		if _, err := os.Stat(filepath.Join(parentDir, name)); err != nil {
+			t.Errorf("expected %s to be kept: %v", name, err)
+		}
+	}
+
+	if err := bm.CleanupOldBackups(0); err == nil {
+		t.Error("expected an error for a retention of 0 days")
+	}
+}
+
+func TestBundleBackup(t *testing.T) {
+	repoDir := t.TempDir()
+	gittest.InitRepository(t, repoDir)
+
+	bm := &BackupManager{BackupDir: t.TempDir(), Strategy: StrategyBundle, Timestamp: "20240101-000000"}
+	info, err := bm.CreateBackup(context.Background(), repoDir, "project")
+	if err != nil {
+		t.Fatalf("CreateBackup returned error: %v", err)
+	}
+	if info.Strategy != StrategyBundle || info.BackupPath != filepath.Join(bm.BackupDir, "project.bundle") {
+		t.Errorf("unexpected backup info: %+v", info)
+	}
+
+	// A repository that is not empty is never overwritten
+	var backupErr *BackupError
+	if err := bm.RestoreBackup(info); !errors.As(err, &backupErr) {
+		t.Errorf("expected a BackupError when the repository path is not empty, got %v", err)
+	}
+
+	info.OriginalPath = filepath.Join(t.TempDir(), "restored")
+	if err := bm.RestoreBackup(info); err != nil {
+		t.Fatalf("RestoreBackup returned error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(info.OriginalPath, "README.md"))
+	if err != nil || string(data) != "# test" {
+		t.Errorf("expected README.md restored from the bundle, got %q (error: %v)", data, err)
+	}
+}
+
+func TestNewBackupManagerStrategy(t *testing.T) {
+	backupDir := t.TempDir()
+
+	bm, err := NewBackupManager(backupDir, "")
+	if err != nil || bm.Strategy != StrategyCopy {
+		t.Errorf("expected an empty strategy to mean '%s', got %v (error: %v)", StrategyCopy, bm, err)
+	}
+
+	if _, err := NewBackupManager(backupDir, "rsync"); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+
+	bm = &BackupManager{BackupDir: backupDir, Strategy: "rsync"}
+	var backupErr *BackupError
+	if _, err := bm.CreateBackup(context.Background(), t.TempDir(), "project"); !errors.As(err, &backupErr) {
+		t.Errorf("expected a BackupError for an unknown strategy, got %v", err)
+	}
+}
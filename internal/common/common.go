@@ -4,11 +4,16 @@ package common
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"reflect"
+	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aeciopires/updateGit/internal/config"
@@ -20,6 +25,32 @@ import (
 	zerolog_pkgerrors "github.com/rs/zerolog/pkgerrors"
 )
 
+// logFile is the file used to write log messages when --log-file is set
+var logFile *os.File
+
+// logWriter is the destination of log messages when --log-file is not set
+var logWriter io.Writer = os.Stdout
+
+// loggerMu serializes the log calls, because each one configures the global zerolog logger.
+// Repositories updated in parallel log from several goroutines.
+var loggerMu sync.Mutex
+
+// SetLogOutput changes the destination of log messages when --log-file is not set.
+// A nil writer restores the default (os.Stdout). Tests use it to capture the log messages:
+//
+//	var buf bytes.Buffer
+//	common.SetLogOutput(&buf)
+//	defer common.SetLogOutput(nil)
+func SetLogOutput(w io.Writer) {
+	if w == nil {
+		w = os.Stdout
+	}
+
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	logWriter = w
+}
+
 // FindExecutable checks if a file exists at the given path and is executable.
 func FindExecutable(path string) (bool, error) {
 	info, err := os.Stat(path)
@@ -41,6 +72,12 @@ func FindExecutable(path string) (bool, error) {
 	return false, nil
 }
 
+// Executable is a custom validator to accept only paths of executable files, checked by FindExecutable
+func Executable(fl validator.FieldLevel) bool {
+	found, err := FindExecutable(fl.Field().String())
+	return err == nil && found
+}
+
 // CreateValidationErrorMessage based on validation tags.
 // If the tag is not recognized, it will return a generic message.
 func CreateValidationErrorMessage(err error, data interface{}) string {
@@ -67,6 +104,8 @@ func CreateValidationErrorMessage(err error, data interface{}) string {
 			message += fmt.Sprintf("%s must be a boolean. ", err.Field())
 		} else if err.Tag() == "string" {
 			message += fmt.Sprintf("%s must be a string. ", err.Field())
+		} else if err.Tag() == "executable" {
+			message += fmt.Sprintf("%s must be an executable file. ", err.Field())
 		} else if err.Tag() == "nefield" {
 			param := GetParamName(data, err.Param())
 			message += fmt.Sprintf("%s must be different from %s. ", err.Field(), param)
@@ -99,6 +138,7 @@ func GetParamName(data interface{}, param string) string {
 
 // Logger print message log accoding level, timestamp and trace.
 // Support the message with same behavour of fmt.Sprintf.
+// Errors formatted with %w stay wrapped in the error logged by the error, fatal and panic levels.
 //
 // References:
 //
@@ -123,44 +163,19 @@ func GetParamName(data interface{}, param string) string {
 func Logger(level string, message string, args ...interface{}) {
 	level = strings.ToLower(level)
 
-	log.Logger = log.Output(zerolog.ConsoleWriter{
-		Out:        os.Stdout,
-		TimeFormat: "2006-01-02 15:04:05",
-		FormatLevel: func(i interface{}) string {
-			return strings.ToUpper(fmt.Sprint(i))
-		},
-		FormatMessage: func(i interface{}) string {
-			return fmt.Sprint(i)
-		},
-		FormatTimestamp: func(i interface{}) string {
-			if ts, ok := i.(string); ok {
-				return ts
-			}
-			if t, ok := i.(time.Time); ok {
-				return t.Format("2006-01-02 15:04:05")
-			}
-			return fmt.Sprint(i)
-		},
-	})
-
-	// Set time some configurations of zerolog
-	zerolog.TimeFieldFormat = time.RFC3339
-	zerolog.ErrorStackMarshaler = zerolog_pkgerrors.MarshalStack
-
-	// Default level is info, unless debug flag is present
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	if config.Debug != nil && *config.Debug {
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
-	}
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	configureLogger()
 
-	// Get the message and arguments from Sprintf
-	formatted := fmt.Sprintf(message, args...)
+	// Get the message and arguments from Errorf, which formats like Sprintf and also accepts %w
+	messageErr := fmt.Errorf(message, args...)
+	formatted := messageErr.Error()
 
 	// Get stack trace with line and file where the error occurred
 	if level == "error" || level == "fatal" || level == "panic" {
 		_, file, line, ok := runtime.Caller(1)
 		if ok {
-			errWithStack := pkgerrors.WithStack(fmt.Errorf("%s (%s:%d)", formatted, file, line))
+			errWithStack := pkgerrors.WithStack(fmt.Errorf("%w (%s:%d)", messageErr, file, line))
 			switch level {
 			case "error":
 				// This log type does not interrupt the program
@@ -187,6 +202,176 @@ func Logger(level string, message string, args ...interface{}) {
 	}
 }
 
+// LoggerWithFields works like Logger, but the message is written as is and each field is
+// added as a key-value pair of the log event, making log aggregation queries easier.
+// Fatal and panic levels interrupt the program like in Logger.
+//
+// Example:
+//
+//	common.LoggerWithFields("info", "Repository updated", map[string]interface{}{
+//		"repository": "updateGit",
+//		"duration":   2 * time.Second,
+//		"changed":    true,
+//	})
+//
+// Output (--log-format json):
+//
+//	{"level":"info","changed":true,"duration":2000,"repository":"updateGit","time":"2025-04-22T19:29:04-03:00","message":"Repository updated"}
+func LoggerWithFields(level string, message string, fields map[string]interface{}) {
+	level = strings.ToLower(level)
+
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	configureLogger()
+
+	var event *zerolog.Event
+	switch level {
+	case "debug":
+		event = log.Debug()
+	case "warn", "warning":
+		event = log.Warn()
+	case "error":
+		event = log.Error()
+	case "fatal":
+		event = log.Fatal()
+	case "panic":
+		event = log.Panic()
+	default:
+		event = log.Info()
+	}
+
+	// Sorted keys keep the same order of fields in every message
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch value := fields[key].(type) {
+		case string:
+			event = event.Str(key, value)
+		case int:
+			event = event.Int(key, value)
+		case int64:
+			event = event.Int64(key, value)
+		case bool:
+			event = event.Bool(key, value)
+		case float64:
+			event = event.Float64(key, value)
+		case time.Duration:
+			event = event.Dur(key, value)
+		case time.Time:
+			event = event.Time(key, value)
+		case error:
+			event = event.AnErr(key, value)
+		default:
+			event = event.Interface(key, value)
+		}
+	}
+
+	event.Msg(message)
+}
+
+// configureLogger sets the output, format and level of the global zerolog logger
+// according to the current configuration
+func configureLogger() {
+	timestampFormat := config.Properties.Output.LogTimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = time.RFC3339
+	}
+
+	// The timezone is validated with the configuration, so an error here falls back to the local time
+	location, err := config.LogLocation(config.Properties.Output.LogTimezone)
+	if err != nil {
+		location = time.Local
+	}
+	zerolog.TimestampFunc = func() time.Time {
+		return time.Now().In(location)
+	}
+
+	out := logOutput()
+	if config.Properties.Output.LogFormat == "json" {
+		// JSON lines are written as is, useful for log aggregators
+		log.Logger = log.Output(out)
+		zerolog.TimeFieldFormat = timestampFormat
+		if timestampFormat == config.LogTimestampUnix {
+			zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+		}
+	} else {
+		// The console writer receives the timestamp in RFC3339 and formats it again
+		zerolog.TimeFieldFormat = time.RFC3339
+		log.Logger = log.Output(zerolog.ConsoleWriter{
+			Out:        out,
+			NoColor:    !config.Properties.Output.Color,
+			TimeFormat: timestampFormat,
+			FormatLevel: func(i interface{}) string {
+				return strings.ToUpper(fmt.Sprint(i))
+			},
+			FormatMessage: func(i interface{}) string {
+				return fmt.Sprint(i)
+			},
+			FormatTimestamp: func(i interface{}) string {
+				return formatLogTimestamp(i, timestampFormat, location)
+			},
+		})
+	}
+
+	// Set time some configurations of zerolog
+	zerolog.ErrorStackMarshaler = zerolog_pkgerrors.MarshalStack
+
+	// Default level is info, unless quiet or debug flag is present
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	if config.Properties.Output.Quiet {
+		zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	}
+	if config.Debug != nil && *config.Debug {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	}
+}
+
+// formatLogTimestamp formats the timestamp of a console log message with layout (a Go time layout
+// or config.LogTimestampUnix), converted to location. Values that are not RFC3339 timestamps are written as is.
+func formatLogTimestamp(i interface{}, layout string, location *time.Location) string {
+	t, ok := i.(time.Time)
+	if ts, isString := i.(string); isString {
+		parsed, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			return ts
+		}
+		t, ok = parsed, true
+	}
+	if !ok {
+		return fmt.Sprint(i)
+	}
+
+	if layout == config.LogTimestampUnix {
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	return t.In(location).Format(layout)
+}
+
+// logOutput returns the destination of log messages.
+// If a log file is configured, it is opened once and reused by the next calls.
+// Otherwise the writer defined by SetLogOutput is used.
+func logOutput() io.Writer {
+	logFilePath := config.Properties.Output.LogFile
+	if logFilePath == "" {
+		return logWriter
+	}
+
+	if logFile == nil || logFile.Name() != logFilePath {
+		file, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, config.PermissionFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[WARNING] Could not open log file '%s', using the default output: %v\n", logFilePath, err)
+			return logWriter
+		}
+		logFile = file
+	}
+
+	return logFile
+}
+
 // StringToEnvVar transform strings to uppercase and substitue '-' by '_' if exists
 func StringToEnvVar(s string) string {
 	s = strings.ToUpper(s)
@@ -196,11 +381,12 @@ func StringToEnvVar(s string) string {
 
 
 // CheckCommandsAvailable verifies if all specified command-line tools are installed
-// and accessible in the system's PATH.
-// It returns a list of missing commands and an error if any are not found.
-// If all commands are found, it returns nil, nil.
-func CheckCommandsAvailable(commands []string) {
+// and accessible in the system's PATH, with at least the minimum version mapped to each command.
+// An empty minimum version only checks the command exists.
+// The application exits if any command is missing or older than its minimum version.
+func CheckCommandsAvailable(commands map[string]string) {
 	missingCommands := []string{}
+	outdatedCommands := []string{}
 
 	if len(commands) == 0 {
 		Logger("debug", "No commands specified for availability check.")
@@ -208,7 +394,13 @@ func CheckCommandsAvailable(commands []string) {
 
 	Logger("debug", "Checking availability of required commands: %v", commands)
 
-	for _, cmdName := range commands {
+	names := make([]string, 0, len(commands))
+	for cmdName := range commands {
+		names = append(names, cmdName)
+	}
+	sort.Strings(names)
+
+	for _, cmdName := range names {
 		if strings.TrimSpace(cmdName) == "" {
 			Logger("warning", "Empty command name provided in the list, skipping.")
 			continue
@@ -223,18 +415,88 @@ func CheckCommandsAvailable(commands []string) {
 			// It could also be a permission issue for directories in PATH, but "not found" is most common.
 			Logger("warning", "Command '%s' not found in system PATH: %v", cmdName, findErr)
 			missingCommands = append(missingCommands, cmdName)
-		} else {
-			Logger("debug", "Command '%s' found in system PATH.", cmdName)
+			continue
+		}
+		Logger("debug", "Command '%s' found in system PATH.", cmdName)
+
+		if err := CheckMinimumVersion(cmdName, commands[cmdName]); err != nil {
+			Logger("warning", "%v", err)
+			outdatedCommands = append(outdatedCommands, cmdName)
 		}
 	}
 
 	if len(missingCommands) > 0 {
 		Logger("fatal", "the following required command(s) were not found in your system PATH: %s. Please install them and ensure they are accessible.", strings.Join(missingCommands, ", "))
 	}
+	if len(outdatedCommands) > 0 {
+		Logger("fatal", "the following required command(s) are older than the minimum version: %s. Please update them.", strings.Join(outdatedCommands, ", "))
+	}
 
 	Logger("debug", "All specified commands (%v) are available in system PATH.", commands)
 }
 
+// versionPattern matches the first version number in the output of "<command> --version",
+// e.g. "2.39.5" in "git version 2.39.5" or "2.39" in "git version 2.39.windows.1"
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// CheckMinimumVersion runs "<command> --version" and returns an error if the version is older than minVersion.
+// An empty minVersion is always satisfied.
+func CheckMinimumVersion(command, minVersion string) error {
+	if minVersion == "" {
+		return nil
+	}
+
+	version, err := CommandVersion(command)
+	if err != nil {
+		return err
+	}
+
+	if CompareVersions(version, minVersion) < 0 {
+		return fmt.Errorf("'%s' version %s is older than the minimum version %s", command, version, minVersion)
+	}
+	Logger("debug", "Command '%s' version %s satisfies the minimum version %s.", command, version, minVersion)
+	return nil
+}
+
+// CommandVersion runs "<command> --version" and returns the first version number of the output, e.g. "2.39.5"
+func CommandVersion(command string) (string, error) {
+	output, err := exec.Command(command, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("could not get the version of '%s': %v", command, err)
+	}
+
+	version := versionPattern.FindString(string(output))
+	if version == "" {
+		return "", fmt.Errorf("could not find the version of '%s' in: %s", command, strings.TrimSpace(string(output)))
+	}
+	return version, nil
+}
+
+// CompareVersions compares two MAJOR.MINOR[.PATCH] versions, returning -1 if a is older than b,
+// 1 if a is newer than b and 0 if they are equal. A missing patch is the same as 0.
+func CompareVersions(a, b string) int {
+	partsA, partsB := versionParts(a), versionParts(b)
+	for i := range partsA {
+		if partsA[i] != partsB[i] {
+			if partsA[i] < partsB[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts returns the major, minor and patch numbers of a version. Missing numbers are 0
+func versionParts(version string) [3]int {
+	var parts [3]int
+	matches := versionPattern.FindStringSubmatch(version)
+	for i := 1; i < len(matches); i++ {
+		parts[i-1], _ = strconv.Atoi(matches[i])
+	}
+	return parts
+}
+
 // FileExists checks if a file exists and is not a directory.
 func FileExists(path string) bool {
 	info, errStat := os.Stat(path)
@@ -0,0 +1,181 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/aeciopires/updateGit/internal/config"
+)
+
+func TestFindExecutable(t *testing.T) {
+	tempDir := t.TempDir()
+
+	t.Run("executable file", func(t *testing.T) {
+		path := filepath.Join(tempDir, "script.sh")
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"), config.PermissionBinary); err != nil {
+			t.Fatalf("could not write file: %v", err)
+		}
+
+		found, err := FindExecutable(path)
+		if err != nil || !found {
+			t.Errorf("expected (true, nil), got (%t, %v)", found, err)
+		}
+	})
+
+	t.Run("file without execute bit", func(t *testing.T) {
+		path := filepath.Join(tempDir, "notes.txt")
+		if err := os.WriteFile(path, []byte("notes"), config.PermissionFile); err != nil {
+			t.Fatalf("could not write file: %v", err)
+		}
+
+		found, err := FindExecutable(path)
+		if err != nil || found {
+			t.Errorf("expected (false, nil), got (%t, %v)", found, err)
+		}
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		found, err := FindExecutable(tempDir)
+		if err != nil || found {
+			t.Errorf("expected (false, nil), got (%t, %v)", found, err)
+		}
+	})
+
+	t.Run("non-existent path", func(t *testing.T) {
+		found, err := FindExecutable(filepath.Join(tempDir, "missing"))
+		if err != nil || found {
+			t.Errorf("expected (false, nil), got (%t, %v)", found, err)
+		}
+	})
+
+	t.Run("permission error", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("root ignores directory permissions")
+		}
+
+		lockedDir := filepath.Join(tempDir, "locked")
+		if err := os.Mkdir(lockedDir, config.PermissionDir); err != nil {
+			t.Fatalf("could not create directory: %v", err)
+		}
+		if err := os.Chmod(lockedDir, 0000); err != nil {
+			t.Fatalf("could not change permissions: %v", err)
+		}
+		t.Cleanup(func() { os.Chmod(lockedDir, config.PermissionDir) })
+
+		found, err := FindExecutable(filepath.Join(lockedDir, "script.sh"))
+		if err == nil || found {
+			t.Errorf("expected (false, error), got (%t, %v)", found, err)
+		}
+	})
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "2.39.5", b: "2.31.0", expected: 1},
+		{a: "2.31.0", b: "2.31", expected: 0},
+		{a: "2.9.1", b: "2.31.0", expected: -1},
+		{a: "git version 2.39.windows.1", b: "2.31.0", expected: 1},
+		{a: "1.10.0", b: "1.9.9", expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			if got := CompareVersions(tt.a, tt.b); got != tt.expected {
+				t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExecutable(t *testing.T) {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.RegisterValidation("executable", Executable); err != nil {
+		t.Fatalf("could not register validator: %v", err)
+	}
+
+	tempDir := t.TempDir()
+	script := filepath.Join(tempDir, "hook.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), config.PermissionBinary); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+	notes := filepath.Join(tempDir, "notes.txt")
+	if err := os.WriteFile(notes, []byte("notes"), config.PermissionFile); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+
+	tests := map[string]bool{
+		script:                               true,
+		notes:                                false,
+		tempDir:                              false,
+		filepath.Join(tempDir, "missing.sh"): false,
+	}
+	for path, valid := range tests {
+		err := validate.Var(path, "executable")
+		if valid && err != nil {
+			t.Errorf("expected %q to be valid, got error: %v", path, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("expected %q to be invalid", path)
+		}
+	}
+}
+
+func TestLoggerWithFieldsTypes(t *testing.T) {
+	saved := config.Properties.Output
+	t.Cleanup(func() {
+		config.Properties.Output = saved
+		SetLogOutput(nil)
+	})
+	config.Properties.Output.LogFormat = "json"
+	config.Properties.Output.LogFile = ""
+	config.Properties.Output.LogTimestampFormat = time.RFC3339
+	config.Properties.Output.LogTimezone = "UTC"
+
+	at := time.Date(2025, 4, 22, 19, 29, 4, 0, time.UTC)
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected interface{}
+	}{
+		{name: "string", value: "updateGit", expected: "updateGit"},
+		{name: "int", value: 3, expected: float64(3)},
+		{name: "int64", value: int64(1) << 40, expected: float64(int64(1) << 40)},
+		{name: "bool", value: true, expected: true},
+		{name: "float64", value: 0.5, expected: 0.5},
+		{name: "duration in milliseconds", value: 2 * time.Second, expected: float64(2000)},
+		{name: "time", value: at, expected: "2025-04-22T19:29:04Z"},
+		{name: "error", value: errors.New("pull failed"), expected: "pull failed"},
+		{name: "other types", value: []string{"api", "web"}, expected: []interface{}{"api", "web"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			SetLogOutput(&buf)
+
+			LoggerWithFields("info", "Repository updated", map[string]interface{}{"field": tt.value})
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("invalid json log line: %v\n%s", err, buf.String())
+			}
+			if !reflect.DeepEqual(entry["field"], tt.expected) {
+				t.Errorf("expected field %#v, got %#v", tt.expected, entry["field"])
+			}
+			if entry["message"] != "Repository updated" || entry["level"] != "info" {
+				t.Errorf("unexpected log line: %s", buf.String())
+			}
+		})
+	}
+}
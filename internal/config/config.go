@@ -3,9 +3,13 @@ package config
 
 import (
 	"os"
+	"reflect"
 	"regexp"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
 )
 
 // // ConfigStruct is a struct defined in the global context of the CLI
@@ -14,21 +18,100 @@ import (
 type Config struct {
 	DefaultConfigFile string `mapstructure:"cli_config_file" validate:"omitempty"`
 
-	Git struct {
-		BaseDir       string `mapstructure:"base_dir" validate:"omitempty"`
-		Parallel      bool   `mapstructure:"parallel_enabled" validate:"omitempty,boolean"`
-		MaxConcurrent int    `mapstructure:"max_concurrent" validate:"omitempty,number"`
-	} `mapstructure:"git"`
+	Git    GitConfig    `mapstructure:"git"`
+	Backup BackupConfig `mapstructure:"backup"`
+	Filter FilterConfig `mapstructure:"filter"`
+	Output OutputConfig `mapstructure:"output"`
+	Hooks  HooksConfig  `mapstructure:"hooks"`
+}
+
+// GitConfig groups the properties of the git section
+type GitConfig struct {
+	BaseDir               string   `mapstructure:"base_dir" validate:"omitempty"`
+	Parallel              bool     `mapstructure:"parallel_enabled" validate:"omitempty,boolean"`
+	MaxConcurrent         int      `mapstructure:"max_concurrent" validate:"omitempty,number"`
+	RepoTimeout           int      `mapstructure:"repo_timeout" validate:"omitempty,min=0"`
+	TotalTimeout          int      `mapstructure:"total_timeout" validate:"omitempty,min=0"`
+	MaxDepth              int      `mapstructure:"scan_depth" validate:"min=0,max=20"`
+	CloneDepth            int      `mapstructure:"clone_depth" validate:"omitempty,min=0"`
+	CloneBranch           string   `mapstructure:"clone_branch" validate:"omitempty"`
+	CloneSingleBranch     bool     `mapstructure:"clone_single_branch" validate:"omitempty,boolean"`
+	CommitMessageTemplate string   `mapstructure:"commit_message_template" validate:"omitempty"`
+	ExtraPullArgs         []string `mapstructure:"extra_pull_args" validate:"omitempty,dive,notManagedPullArg"`
+	PullStrategy          string   `mapstructure:"pull_strategy" validate:"omitempty,oneof=merge rebase ff-only"`
+	MaxPackSize           int      `mapstructure:"max_pack_size" validate:"omitempty,min=0"`
+	HTTPLowSpeedLimit     int      `mapstructure:"http_low_speed_limit" validate:"omitempty,min=0"`
+	HTTPLowSpeedTime      int      `mapstructure:"http_low_speed_time" validate:"omitempty,min=0"`
+	CredentialHelper      string   `mapstructure:"credential_helper" validate:"omitempty"`
+	SafeDirectory         string   `mapstructure:"safe_directory" validate:"omitempty"`
+	SignCommits           bool     `mapstructure:"sign_commits" validate:"omitempty,boolean"`
+	GPGKeyID              string   `mapstructure:"gpg_key_id" validate:"omitempty"`
+	FetchDepth            int      `mapstructure:"fetch_depth" validate:"omitempty,min=0"`
+	UserName              string   `mapstructure:"user_name" validate:"omitempty"`
+	UserEmail             string   `mapstructure:"user_email" validate:"omitempty,email"`
+	TagAfterPull          string   `mapstructure:"tag_after_pull" validate:"omitempty"`
+	TagMessage            string   `mapstructure:"tag_message" validate:"omitempty"`
+	PushTags              bool     `mapstructure:"push_tags" validate:"omitempty,boolean"`
+	ForceTag              bool     `mapstructure:"force_tag" validate:"omitempty,boolean"`
+	WorktreePrune         bool     `mapstructure:"worktree_prune" validate:"omitempty,boolean"`
+	IncludeSubmoduleRepos bool     `mapstructure:"include_submodule_repos" validate:"omitempty,boolean"`
+	PullOnlyIfBehind      bool     `mapstructure:"pull_only_if_behind" validate:"omitempty,boolean"`
+	AutoStash             bool     `mapstructure:"auto_stash" validate:"omitempty,boolean"`
+	PostPullDiffStat      bool     `mapstructure:"post_pull_diff_stat" validate:"omitempty,boolean"`
+	FetchAllRemotes       bool     `mapstructure:"fetch_all_remotes" validate:"omitempty,boolean"`
+	FetchPrune            bool     `mapstructure:"fetch_prune" validate:"omitempty,boolean"`
+	FetchBeforePull       bool     `mapstructure:"fetch_before_pull" validate:"omitempty,boolean"`
+	UpdateSubmodules      bool     `mapstructure:"update_submodules" validate:"omitempty,boolean"`
+	SubmoduleJobs         int      `mapstructure:"submodule_jobs" validate:"omitempty,min=0"`
+	SubmoduleDepth        int      `mapstructure:"submodule_depth" validate:"omitempty,min=0"`
+}
+
+// BackupConfig groups the properties of the backup section
+type BackupConfig struct {
+	Enabled   bool   `mapstructure:"enabled" validate:"omitempty,boolean"`
+	Directory string `mapstructure:"directory" validate:"omitempty"`
+	Strategy  string `mapstructure:"strategy" validate:"omitempty,alpha,lowercase,oneof=copy stash auto bundle"`
+	// ExcludeGitDir skips the .git directory in copy backups
+	ExcludeGitDir bool `mapstructure:"exclude_git_dir" validate:"omitempty,boolean"`
+	// StashDropOnRestore drops the stash entry after a stash backup is restored
+	StashDropOnRestore bool `mapstructure:"stash_drop_on_restore" validate:"omitempty,boolean"`
+	// RetentionDays removes the backups older than the number of days after a successful pull. 0 keeps all backups
+	RetentionDays int `mapstructure:"retention_days" validate:"omitempty,min=0"`
+}
 
-	Backup struct {
-		Enabled   bool   `mapstructure:"enabled" validate:"omitempty,boolean"`
-		Directory string `mapstructure:"directory" validate:"omitempty"`
-		Strategy  string `mapstructure:"strategy" validate:"omitempty,alpha,lowercase,oneof=copy stash"`
-	} `mapstructure:"backup"`
+// FilterConfig groups the properties of the filter section
+type FilterConfig struct {
+	SkipRepos       []string `mapstructure:"skip_repos" validate:"omitempty"`
+	IncludePatterns []string `mapstructure:"include_patterns" validate:"omitempty"`
+	ExcludePatterns []string `mapstructure:"exclude_patterns" validate:"omitempty"`
+	// ListSkipped prints the repositories excluded by the filter and the reason after the pull
+	ListSkipped bool `mapstructure:"list_skipped" validate:"omitempty,boolean"`
+}
+
+// HooksConfig groups the properties of the hooks section.
+// The scripts are validated by the "executable" validator registered in cmd/root.go
+type HooksConfig struct {
+	// PrePull is a script executed before the pull of each repository
+	PrePull string `mapstructure:"pre_pull" validate:"omitempty,executable"`
+	// PostPull is a script executed after the pull of each repository
+	PostPull string `mapstructure:"post_pull" validate:"omitempty,executable"`
+	// StrictMode fails the update of the repository when a hook fails
+	StrictMode bool `mapstructure:"strict_mode" validate:"omitempty,boolean"`
+}
 
-	Filter struct {
-		SkipRepos []string `mapstructure:"skip_repos" validate:"omitempty"`
-	} `mapstructure:"filter"`
+// OutputConfig groups the properties of the output section
+type OutputConfig struct {
+	Format    string `mapstructure:"format" validate:"omitempty,oneof=text json yaml csv"`
+	LogFormat string `mapstructure:"log_format" validate:"omitempty,oneof=console json"`
+	LogFile   string `mapstructure:"log_file" validate:"omitempty"`
+	Quiet     bool   `mapstructure:"quiet" validate:"omitempty,boolean"`
+	Color     bool   `mapstructure:"color" validate:"omitempty,boolean"`
+	// LogTimestampFormat is a Go time layout or "unix" for Unix epoch seconds
+	LogTimestampFormat string `mapstructure:"log_timestamp_format" validate:"omitempty,timeLayout"`
+	// LogTimezone is "local", "utc" or an IANA timezone name (e.g. America/New_York)
+	LogTimezone string `mapstructure:"log_timezone" validate:"omitempty,timezone"`
+	// Template is a file with a Go template rendered with the summary of the pull
+	Template string `mapstructure:"template" validate:"omitempty,file"`
 }
 
 // Global variables
@@ -43,10 +126,13 @@ var (
 	CLIName           = "updateGit"
 	CLICheckSumBinDir = "bin/"
 
-	// CommandsToCheck is a list of commands to check if they are installed
-	// and available in the PATH environment variable.
-	// Separated by comma.
-	CommandsToCheck = []string{"git"}
+	// CommandsToCheck maps the commands that must be installed and available in the PATH
+	// environment variable to their minimum version. An empty version only checks the command exists.
+	CommandsToCheck = map[string]string{"git": ""}
+
+	// GitConfigEnvMinVersion is the first git version supporting GIT_CONFIG_COUNT, used to pass
+	// the git settings of updateGit to git commands. It is only required when one of them is enabled.
+	GitConfigEnvMinVersion = "2.31.0"
 
 	// Properties is a global variable of PropertiesStruct type
 	Properties Config
@@ -54,11 +140,6 @@ var (
 	// Log configurations
 	Debug *bool
 
-	//----------------------------
-	// Git configurations
-	//----------------------------
-	Timeout int = 30 // Default timeout for git operations in seconds
-
 	//----------------------------
 	// Linux/Unix configurations
 	//----------------------------
@@ -88,11 +169,178 @@ func SetDefaultConfig() {
 	Properties.Git.BaseDir = "./git_repos"
 	Properties.Git.Parallel = true
 	Properties.Git.MaxConcurrent = 10
+	// 0 uses the timeout of the git package (git.DefaultRepoTimeoutSec)
+	Properties.Git.RepoTimeout = 0
+	// Same as git.DefaultTotalTimeoutSec. 0 means no limit
+	Properties.Git.TotalTimeout = 1800
+	// Levels of directories scanned for repositories below the base directory. 0 means unlimited
+	Properties.Git.MaxDepth = 1
+	// 0 means full history
+	Properties.Git.CloneDepth = 0
+	// Empty value means the default branch of the remote
+	Properties.Git.CloneBranch = ""
+	Properties.Git.CloneSingleBranch = false
+	// Empty value keeps the message generated by git
+	Properties.Git.CommitMessageTemplate = ""
+	Properties.Git.ExtraPullArgs = []string{}
+	// Empty value keeps the strategy of the git configuration (pull.rebase and pull.ff)
+	Properties.Git.PullStrategy = ""
+	// Network limits passed to git. 0 keeps the git configuration of the user
+	// MaxPackSize is in MiB (pack.windowMemory), HTTPLowSpeedLimit in bytes per second and HTTPLowSpeedTime in seconds
+	Properties.Git.MaxPackSize = 0
+	Properties.Git.HTTPLowSpeedLimit = 0
+	Properties.Git.HTTPLowSpeedTime = 0
+	// Empty uses the credential helpers of the git configuration
+	Properties.Git.CredentialHelper = ""
+	// Repositories owned by other users are refused by git ("dubious ownership") unless they are safe directories
+	Properties.Git.SafeDirectory = ""
+	// Empty GPGKeyID uses the key of the git configuration (user.signingKey or the committer email)
+	Properties.Git.SignCommits = false
+	Properties.Git.GPGKeyID = ""
+	// Depth of the fetch executed before the pull. 0 means the fetch is not limited
+	Properties.Git.FetchDepth = 0
+	// Identity of the merge commits created by pull. Empty values use the git configuration
+	Properties.Git.UserName = ""
+	Properties.Git.UserEmail = ""
+	// Empty TagAfterPull disables the tag after pull. Empty TagMessage uses the default message of the git package
+	Properties.Git.TagAfterPull = ""
+	Properties.Git.TagMessage = ""
+	Properties.Git.PushTags = false
+	Properties.Git.ForceTag = false
+	Properties.Git.WorktreePrune = false
+	// Repositories that are submodules of the base directory repository are updated by their parent
+	Properties.Git.IncludeSubmoduleRepos = false
+	// Fetch first and skip the pull when the upstream has no new commits
+	Properties.Git.PullOnlyIfBehind = false
+	// Repositories with uncommitted changes are pulled as they are, which may fail
+	Properties.Git.AutoStash = false
+	Properties.Git.PostPullDiffStat = false
+	// Fetch every remote (e.g. upstream, fork, mirror) before the pull of the current branch
+	Properties.Git.FetchAllRemotes = false
+	// Remote-tracking branches deleted from the remotes are kept by the fetch of FetchAllRemotes and FetchBeforePull
+	Properties.Git.FetchPrune = false
+	// The pull fetches only the upstream of the current branch
+	Properties.Git.FetchBeforePull = false
+	// Submodules are not updated after the pull. Jobs and depth 0 keep the defaults of git submodule update
+	Properties.Git.UpdateSubmodules = false
+	Properties.Git.SubmoduleJobs = 0
+	Properties.Git.SubmoduleDepth = 0
 	Properties.Backup.Enabled = false
 	// Attention!!! The validator do not support ˜, $HOME or file globbing in values.
 	Properties.Backup.Directory = "./backups"
 	Properties.Backup.Strategy = "copy"
+	// Copy backups have only the working tree. With false they are standalone clones, but the .git
+	// directory has the whole history and is often bigger than the working tree
+	Properties.Backup.ExcludeGitDir = true
+	// Restored stash backups are kept until the user drops them
+	Properties.Backup.StashDropOnRestore = false
+	// Old backups are only removed when the user sets a retention
+	Properties.Backup.RetentionDays = 0
 	Properties.Filter.SkipRepos = []string{}
+	Properties.Filter.IncludePatterns = []string{}
+	Properties.Filter.ExcludePatterns = []string{}
+	Properties.Output.Format = "text"
+	Properties.Output.LogFormat = "console"
+	// Empty value means that log messages are written to stdout
+	Properties.Output.LogFile = ""
+	Properties.Output.Quiet = false
+	Properties.Output.Color = true
+	Properties.Output.LogTimestampFormat = time.RFC3339
+	Properties.Output.LogTimezone = LogTimezoneLocal
+	// Empty value means that no summary is rendered
+	Properties.Output.Template = ""
+	// No hook is configured by default
+	Properties.Hooks.PrePull = ""
+	Properties.Hooks.PostPull = ""
+	Properties.Hooks.StrictMode = false
+}
+
+// SetViperDefaults registers the current values of Properties as Viper defaults,
+// using the mapstructure tags as keys (e.g. git.base_dir).
+// It must be called after the flags are parsed, so values of CLI options survive
+// the Unmarshal when a key is missing in the config file and environment variables.
+func SetViperDefaults() {
+	setViperDefaults(reflect.ValueOf(Properties), "")
+}
+
+// setViperDefaults walks the struct fields recursively, registering each leaf value
+func setViperDefaults(value reflect.Value, prefix string) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		key := field.Tag.Get("mapstructure")
+		if !field.IsExported() || key == "" {
+			continue
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		if value.Field(i).Kind() == reflect.Struct {
+			setViperDefaults(value.Field(i), key)
+			continue
+		}
+		viper.SetDefault(key, value.Field(i).Interface())
+	}
+}
+
+// ManagedPullArgs are git pull arguments controlled by updateGit itself.
+// They can not be informed in Git.ExtraPullArgs.
+var ManagedPullArgs = []string{"--rebase", "-r", "--no-rebase", "--ff-only", "--ff", "--no-ff"}
+
+// NotManagedPullArg is a custom validator to reject git pull arguments listed in ManagedPullArgs,
+// including the --option=value form
+func NotManagedPullArg(fl validator.FieldLevel) bool {
+	arg, _, _ := strings.Cut(fl.Field().String(), "=")
+	for _, managed := range ManagedPullArgs {
+		if arg == managed {
+			return false
+		}
+	}
+	return true
+}
+
+// LogTimestampUnix is the value of Output.LogTimestampFormat for Unix epoch timestamps
+const LogTimestampUnix = "unix"
+
+// TimeLayout is a custom validator to accept only Go time layouts that can be parsed back
+// after formatting the current time, or LogTimestampUnix.
+// A layout without any time element (e.g. "foo") is rejected, because it formats to itself.
+func TimeLayout(fl validator.FieldLevel) bool {
+	layout := fl.Field().String()
+	if layout == LogTimestampUnix {
+		return true
+	}
+
+	formatted := time.Now().Format(layout)
+	if formatted == layout {
+		return false
+	}
+	_, err := time.Parse(layout, formatted)
+	return err == nil
+}
+
+// Values of Output.LogTimezone besides the IANA timezone names
+const (
+	LogTimezoneLocal = "local"
+	LogTimezoneUTC   = "utc"
+)
+
+// LogLocation returns the location of a value of Output.LogTimezone.
+// An empty value is the same as LogTimezoneLocal.
+func LogLocation(timezone string) (*time.Location, error) {
+	switch strings.ToLower(timezone) {
+	case "", LogTimezoneLocal:
+		return time.Local, nil
+	case LogTimezoneUTC:
+		return time.UTC, nil
+	}
+	return time.LoadLocation(timezone)
+}
+
+// Timezone is a custom validator to accept only values of Output.LogTimezone known by LogLocation
+func Timezone(fl validator.FieldLevel) bool {
+	_, err := LogLocation(fl.Field().String())
+	return err == nil
 }
 
 // NoUnderscores is a custom validator to reject string with underscore '_'
@@ -100,3 +348,49 @@ func NoUnderscores(fl validator.FieldLevel) bool {
 	matched, _ := regexp.MatchString(`_`, fl.Field().String())
 	return !matched
 }
+
+// UnmarshalGitConfig reads only the git section loaded by Viper.
+// It avoids unmarshaling the whole struct when just this section changed.
+func UnmarshalGitConfig() (*GitConfig, error) {
+	cfg := Properties.Git
+	if err := viper.UnmarshalKey("git", &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// UnmarshalBackupConfig reads only the backup section loaded by Viper.
+func UnmarshalBackupConfig() (*BackupConfig, error) {
+	cfg := Properties.Backup
+	if err := viper.UnmarshalKey("backup", &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// UnmarshalFilterConfig reads only the filter section loaded by Viper.
+func UnmarshalFilterConfig() (*FilterConfig, error) {
+	cfg := Properties.Filter
+	if err := viper.UnmarshalKey("filter", &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// UnmarshalOutputConfig reads only the output section loaded by Viper.
+func UnmarshalOutputConfig() (*OutputConfig, error) {
+	cfg := Properties.Output
+	if err := viper.UnmarshalKey("output", &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// UnmarshalHooksConfig reads only the hooks section loaded by Viper.
+func UnmarshalHooksConfig() (*HooksConfig, error) {
+	cfg := Properties.Hooks
+	if err := viper.UnmarshalKey("hooks", &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
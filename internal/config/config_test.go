@@ -0,0 +1,293 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/viper"
+)
+
+// zeroValueDefaults lists the fields whose default value is intentionally the zero value.
+// A new field must be added to SetDefaultConfig or, if zero is the right default, to this list.
+var zeroValueDefaults = map[string]bool{
+	"Git.RepoTimeout":           true,
+	"Git.CloneDepth":            true,
+	"Git.CloneBranch":           true,
+	"Git.CloneSingleBranch":     true,
+	"Git.CommitMessageTemplate": true,
+	"Git.PullStrategy":          true,
+	"Git.MaxPackSize":           true,
+	"Git.HTTPLowSpeedLimit":     true,
+	"Git.HTTPLowSpeedTime":      true,
+	"Git.CredentialHelper":      true,
+	"Git.SafeDirectory":         true,
+	"Git.SignCommits":           true,
+	"Git.GPGKeyID":              true,
+	"Git.FetchDepth":            true,
+	"Git.UserName":              true,
+	"Git.UserEmail":             true,
+	"Git.TagAfterPull":          true,
+	"Git.TagMessage":            true,
+	"Git.PushTags":              true,
+	"Git.ForceTag":              true,
+	"Git.WorktreePrune":         true,
+	"Git.IncludeSubmoduleRepos": true,
+	"Git.PullOnlyIfBehind":      true,
+	"Git.AutoStash":             true,
+	"Git.PostPullDiffStat":      true,
+	"Git.FetchAllRemotes":       true,
+	"Git.FetchPrune":            true,
+	"Git.FetchBeforePull":       true,
+	"Git.UpdateSubmodules":      true,
+	"Git.SubmoduleJobs":         true,
+	"Git.SubmoduleDepth":        true,
+	"Output.Template":           true,
+	"Filter.ListSkipped":        true,
+	"Backup.StashDropOnRestore": true,
+	"Backup.RetentionDays":      true,
+	"Backup.Enabled":            true,
+	"Output.LogFile":            true,
+	"Output.Quiet":              true,
+	"Hooks.PrePull":             true,
+	"Hooks.PostPull":            true,
+	"Hooks.StrictMode":          true,
+}
+
+func TestSetDefaultConfigCoversAllFields(t *testing.T) {
+	Properties = Config{}
+	SetDefaultConfig()
+
+	checkFieldsNotZero(t, reflect.ValueOf(Properties), "")
+}
+
+// checkFieldsNotZero walks the exported fields of a struct (including nested structs)
+// and reports the fields that were left with the zero value.
+func checkFieldsNotZero(t *testing.T, value reflect.Value, prefix string) {
+	t.Helper()
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := prefix + field.Name
+		fieldValue := value.Field(i)
+
+		if fieldValue.Kind() == reflect.Struct {
+			checkFieldsNotZero(t, fieldValue, name+".")
+			continue
+		}
+
+		if fieldValue.IsZero() && !zeroValueDefaults[name] {
+			t.Errorf("field %s is not initialized by SetDefaultConfig", name)
+		}
+	}
+}
+
+func TestSetViperDefaults(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	Properties = Config{}
+	SetDefaultConfig()
+	Properties.Git.MaxConcurrent = 42
+	SetViperDefaults()
+
+	if got := viper.GetString("git.base_dir"); got != Properties.Git.BaseDir {
+		t.Errorf("expected git.base_dir default '%s', got '%s'", Properties.Git.BaseDir, got)
+	}
+	if got := viper.GetInt("git.max_concurrent"); got != 42 {
+		t.Errorf("expected git.max_concurrent default 42, got %d", got)
+	}
+
+	var loaded Config
+	if err := viper.Unmarshal(&loaded); err != nil {
+		t.Fatalf("could not unmarshal defaults: %v", err)
+	}
+	if loaded.Backup.Strategy != Properties.Backup.Strategy || loaded.Output.Format != Properties.Output.Format {
+		t.Errorf("defaults were not preserved by Unmarshal: %+v", loaded)
+	}
+}
+
+func TestUnmarshalSectionConfig(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	Properties = Config{}
+	SetDefaultConfig()
+	viper.Set("git.max_concurrent", 3)
+	viper.Set("backup.strategy", "stash")
+	viper.Set("filter.skip_repos", []string{"legacy"})
+	viper.Set("output.format", "json")
+	viper.Set("hooks.strict_mode", true)
+
+	gitConfig, err := UnmarshalGitConfig()
+	if err != nil {
+		t.Fatalf("UnmarshalGitConfig returned error: %v", err)
+	}
+	// Keys missing in Viper keep the values of Properties
+	if gitConfig.MaxConcurrent != 3 || gitConfig.BaseDir != Properties.Git.BaseDir {
+		t.Errorf("unexpected git section: %+v", gitConfig)
+	}
+	backupConfig, err := UnmarshalBackupConfig()
+	if err != nil {
+		t.Fatalf("UnmarshalBackupConfig returned error: %v", err)
+	}
+	if backupConfig.Strategy != "stash" || backupConfig.Directory != Properties.Backup.Directory {
+		t.Errorf("unexpected backup section: %+v", backupConfig)
+	}
+	filterConfig, err := UnmarshalFilterConfig()
+	if err != nil {
+		t.Fatalf("UnmarshalFilterConfig returned error: %v", err)
+	}
+	if len(filterConfig.SkipRepos) != 1 || filterConfig.SkipRepos[0] != "legacy" {
+		t.Errorf("unexpected filter section: %+v", filterConfig)
+	}
+	outputConfig, err := UnmarshalOutputConfig()
+	if err != nil {
+		t.Fatalf("UnmarshalOutputConfig returned error: %v", err)
+	}
+	if outputConfig.Format != "json" || outputConfig.LogFormat != Properties.Output.LogFormat {
+		t.Errorf("unexpected output section: %+v", outputConfig)
+	}
+	hooksConfig, err := UnmarshalHooksConfig()
+	if err != nil {
+		t.Fatalf("UnmarshalHooksConfig returned error: %v", err)
+	}
+	if !hooksConfig.StrictMode {
+		t.Errorf("unexpected hooks section: %+v", hooksConfig)
+	}
+
+	// Properties is not changed
+	if Properties.Git.MaxConcurrent == 3 || Properties.Output.Format == "json" {
+		t.Errorf("expected Properties to keep the defaults, got %+v", Properties)
+	}
+
+	viper.Set("git.max_concurrent", "many")
+	if _, err := UnmarshalGitConfig(); err == nil {
+		t.Error("expected an error for an invalid git.max_concurrent")
+	}
+}
+
+func TestNoUnderscores(t *testing.T) {
+	// Register the custom validator the same way as cmd/root.go
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.RegisterValidation("noUnderscore", NoUnderscores); err != nil {
+		t.Fatalf("could not register validator: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{name: "with underscores", value: "my_repo_name", valid: false},
+		{name: "without underscores", value: "myreponame", valid: true},
+		{name: "empty string", value: "", valid: true},
+		{name: "other punctuation", value: "my-repo.name:v1/2", valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Var(tt.value, "noUnderscore")
+			if tt.valid && err != nil {
+				t.Errorf("expected %q to be valid, got error: %v", tt.value, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("expected %q to be invalid", tt.value)
+			}
+		})
+	}
+}
+
+func TestNotManagedPullArg(t *testing.T) {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.RegisterValidation("notManagedPullArg", NotManagedPullArg); err != nil {
+		t.Fatalf("could not register validator: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		args  []string
+		valid bool
+	}{
+		{name: "no arguments", args: []string{}, valid: true},
+		{name: "unmanaged arguments", args: []string{"--no-recurse-submodules", "--verify-signatures"}, valid: true},
+		{name: "managed argument", args: []string{"--verify-signatures", "--no-rebase"}, valid: false},
+		{name: "managed argument with value", args: []string{"--rebase=merges"}, valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Var(tt.args, "dive,notManagedPullArg")
+			if tt.valid && err != nil {
+				t.Errorf("expected %v to be valid, got error: %v", tt.args, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("expected %v to be invalid", tt.args)
+			}
+		})
+	}
+}
+
+func TestTimeLayout(t *testing.T) {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.RegisterValidation("timeLayout", TimeLayout); err != nil {
+		t.Fatalf("could not register validator: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		layout string
+		valid  bool
+	}{
+		{name: "RFC3339", layout: "2006-01-02T15:04:05Z07:00", valid: true},
+		{name: "custom layout", layout: "2006-01-02 15:04:05", valid: true},
+		{name: "unix epoch", layout: LogTimestampUnix, valid: true},
+		{name: "text without time elements", layout: "timestamp", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Var(tt.layout, "timeLayout")
+			if tt.valid && err != nil {
+				t.Errorf("expected %q to be valid, got error: %v", tt.layout, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("expected %q to be invalid", tt.layout)
+			}
+		})
+	}
+}
+
+func TestTimezone(t *testing.T) {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.RegisterValidation("timezone", Timezone); err != nil {
+		t.Fatalf("could not register validator: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		timezone string
+		valid    bool
+	}{
+		{name: "local", timezone: LogTimezoneLocal, valid: true},
+		{name: "utc in upper case", timezone: "UTC", valid: true},
+		{name: "IANA name", timezone: "America/New_York", valid: true},
+		{name: "unknown name", timezone: "Mars/Olympus_Mons", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Var(tt.timezone, "timezone")
+			if tt.valid && err != nil {
+				t.Errorf("expected %q to be valid, got error: %v", tt.timezone, err)
+			}
+			if !tt.valid && err == nil {
+				t.Errorf("expected %q to be invalid", tt.timezone)
+			}
+		})
+	}
+}
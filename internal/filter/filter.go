@@ -3,12 +3,28 @@
 package filter
 
 import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
 	"github.com/aeciopires/updateGit/internal/common"
 )
 
 // Filter represents repository filtering configuration
 type Filter struct {
-	SkipRepos      map[string]bool
+	SkipRepos map[string]bool
+	// IncludePatterns process only the repositories matching any of them. Empty processes all repositories
+	IncludePatterns []*regexp.Regexp
+	// ExcludePatterns skip the repositories matching any of them
+	ExcludePatterns []*regexp.Regexp
+}
+
+// serializedFilter is the JSON representation of a Filter
+type serializedFilter struct {
+	SkipRepos       []string `json:"skip_repos"`
+	IncludePatterns []string `json:"include_patterns,omitempty"`
+	ExcludePatterns []string `json:"exclude_patterns,omitempty"`
 }
 
 // FilterError represents a filtering error
@@ -21,28 +37,79 @@ func (e *FilterError) Error() string {
 	return "filter pattern '" + e.Pattern + "' error: " + e.Err.Error()
 }
 
-// NewFilter creates a new repository filter with the given patterns
-func NewFilter(skipRepos []string) (*Filter, error) {
+// NewFilter creates a new repository filter with the given patterns.
+// Empty include or exclude patterns are ignored.
+func NewFilter(skipRepos, includePatterns, excludePatterns []string) (*Filter, error) {
 	filter := &Filter{
 		SkipRepos: make(map[string]bool),
 	}
 
+	var err error
+	if filter.IncludePatterns, err = compilePatterns(includePatterns); err != nil {
+		return nil, err
+	}
+	if filter.ExcludePatterns, err = compilePatterns(excludePatterns); err != nil {
+		return nil, err
+	}
+
 	// Build skip repos map
 	for _, repo := range skipRepos {
 		filter.SkipRepos[repo] = true
 		common.Logger("debug", "Repository added to skip list. repository=%s", repo)
 	}
 
-	common.Logger("info", "Repository filter configured. skip_count=%d", len(skipRepos))
+	common.Logger("info", "Repository filter configured. skip_count=%d include_patterns=%v exclude_patterns=%v", len(skipRepos), filter.IncludePatterns, filter.ExcludePatterns)
 
 	return filter, nil
 }
 
+// compilePatterns compiles each pattern, returning a FilterError for the first invalid one
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, &FilterError{Pattern: pattern, Err: err}
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// matchAny reports whether the name matches any of the patterns
+func matchAny(patterns []*regexp.Regexp, name string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// patternStrings returns the source text of the patterns
+func patternStrings(patterns []*regexp.Regexp) []string {
+	var sources []string
+	for _, pattern := range patterns {
+		sources = append(sources, pattern.String())
+	}
+	return sources
+}
+
+// Reasons returned by Explain for the skipped repositories
+const (
+	ReasonSkipList        = "in skip list"
+	ReasonExcludePattern  = "matches exclude pattern"
+	ReasonIncludeMismatch = "does not match include pattern"
+)
+
 // ShouldProcess determines if a repository should be processed based on filter criteria
 func (f *Filter) ShouldProcess(repoName string) bool {
-	// Check skip list first
-	if f.SkipRepos[repoName] {
-		common.Logger("debug", "Repository skipped (in skip list). repository=%s", repoName)
+	reason := f.Explain(repoName)
+	if reason != "" {
+		common.Logger("debug", "Repository skipped (%s). repository=%s", reason, repoName)
 		return false
 	}
 
@@ -50,15 +117,92 @@ func (f *Filter) ShouldProcess(repoName string) bool {
 	return true
 }
 
+// Explain returns the reason why the repository is skipped by the filter, or an empty string when it is processed.
+// The skip list is checked first and the exclude patterns have priority over the include patterns.
+func (f *Filter) Explain(repoName string) string {
+	if f.SkipRepos[repoName] {
+		return ReasonSkipList
+	}
+	if matchAny(f.ExcludePatterns, repoName) {
+		return ReasonExcludePattern
+	}
+	if len(f.IncludePatterns) > 0 && !matchAny(f.IncludePatterns, repoName) {
+		return ReasonIncludeMismatch
+	}
+	return ""
+}
+
+// Match reports whether the repository passes the filter. It is the same as ShouldProcess and
+// makes Filter implement git.RepositoryFilter
+func (f *Filter) Match(repoName string) bool {
+	return f.ShouldProcess(repoName)
+}
+
+// Clone returns a copy of the filter that can be changed without changing f.
+// The pattern slices are copied, but the compiled patterns are shared, as a regexp.Regexp is immutable.
+func (f *Filter) Clone() *Filter {
+	clone := &Filter{
+		SkipRepos:       make(map[string]bool, len(f.SkipRepos)),
+		IncludePatterns: append([]*regexp.Regexp(nil), f.IncludePatterns...),
+		ExcludePatterns: append([]*regexp.Regexp(nil), f.ExcludePatterns...),
+	}
+	for repo, skip := range f.SkipRepos {
+		clone.SkipRepos[repo] = skip
+	}
+	return clone
+}
+
+// WithExtraSkipRepos returns a clone of the filter that also skips repos. f is not changed
+func (f *Filter) WithExtraSkipRepos(repos ...string) *Filter {
+	clone := f.Clone()
+	for _, repo := range repos {
+		clone.SkipRepos[repo] = true
+	}
+	return clone
+}
+
 // GetStats returns filtering statistics
 func (f *Filter) GetStats() map[string]interface{} {
 	stats := map[string]interface{}{
-		"skip_count":          len(f.SkipRepos),
+		"skip_count":    len(f.SkipRepos),
+		"include_count": len(f.IncludePatterns),
+		"exclude_count": len(f.ExcludePatterns),
 	}
 
 	return stats
 }
 
+// Serialize marshals the filter to JSON, keeping the skip list (sorted) and the pattern strings,
+// so it can be persisted and reconstructed later by DeserializeFilter
+func (f *Filter) Serialize() ([]byte, error) {
+	state := serializedFilter{
+		SkipRepos: make([]string, 0, len(f.SkipRepos)),
+	}
+
+	for repo, skip := range f.SkipRepos {
+		if skip {
+			state.SkipRepos = append(state.SkipRepos, repo)
+		}
+	}
+	sort.Strings(state.SkipRepos)
+
+	state.IncludePatterns = patternStrings(f.IncludePatterns)
+	state.ExcludePatterns = patternStrings(f.ExcludePatterns)
+
+	return json.Marshal(state)
+}
+
+// DeserializeFilter reconstructs a filter from the JSON created by Serialize.
+// The patterns are compiled again, so an invalid pattern returns a FilterError.
+func DeserializeFilter(data []byte) (*Filter, error) {
+	var state serializedFilter
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid serialized filter: %w", err)
+	}
+
+	return NewFilter(state.SkipRepos, state.IncludePatterns, state.ExcludePatterns)
+}
+
 // FilterRepositories applies the filter to a list of repository names
 func (f *Filter) FilterRepositories(repos []string) []string {
 	var filtered []string
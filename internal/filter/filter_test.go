@@ -0,0 +1,228 @@
+package filter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aeciopires/updateGit/internal/common"
+	"github.com/aeciopires/updateGit/internal/config"
+)
+
+// captureOutput runs fn with debug mode enabled and returns the log messages
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+
+	debug := true
+	oldDebug := config.Debug
+	t.Cleanup(func() {
+		config.Debug = oldDebug
+		common.SetLogOutput(nil)
+	})
+	config.Debug = &debug
+
+	var output bytes.Buffer
+	common.SetLogOutput(&output)
+
+	fn()
+
+	return output.String()
+}
+
+func TestShouldProcess(t *testing.T) {
+	tests := []struct {
+		name            string
+		skipRepos       []string
+		includePatterns []string
+		excludePatterns []string
+		repoName        string
+		expected        bool
+		expectedLog     string
+	}{
+		{
+			name:        "repository in skip list",
+			skipRepos:   []string{"old-project"},
+			repoName:    "old-project",
+			expected:    false,
+			expectedLog: "Repository skipped (in skip list). repository=old-project",
+		},
+		{
+			name:            "repository matches exclude pattern",
+			excludePatterns: []string{"^archived-"},
+			repoName:        "archived-api",
+			expected:        false,
+			expectedLog:     "Repository skipped (matches exclude pattern). repository=archived-api",
+		},
+		{
+			name:            "include pattern set but repository does not match",
+			includePatterns: []string{"^service-"},
+			repoName:        "frontend",
+			expected:        false,
+			expectedLog:     "Repository skipped (does not match include pattern). repository=frontend",
+		},
+		{
+			name:        "no patterns set",
+			repoName:    "anything",
+			expected:    true,
+			expectedLog: "Repository passes filter criteria. repository=anything",
+		},
+		{
+			name:            "repository matches include but not exclude",
+			includePatterns: []string{"^service-"},
+			excludePatterns: []string{"-legacy$"},
+			repoName:        "service-billing",
+			expected:        true,
+			expectedLog:     "Repository passes filter criteria. repository=service-billing",
+		},
+		{
+			name:            "repository matches the second include pattern",
+			includePatterns: []string{"^service-", "^lib-"},
+			repoName:        "lib-auth",
+			expected:        true,
+			expectedLog:     "Repository passes filter criteria. repository=lib-auth",
+		},
+		{
+			name:            "repository matches the second exclude pattern",
+			includePatterns: []string{"^service-"},
+			excludePatterns: []string{"-legacy$", "-archived$"},
+			repoName:        "service-archived",
+			expected:        false,
+			expectedLog:     "Repository skipped (matches exclude pattern). repository=service-archived",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFilter(tt.skipRepos, tt.includePatterns, tt.excludePatterns)
+			if err != nil {
+				t.Fatalf("NewFilter returned error: %v", err)
+			}
+
+			var result bool
+			output := captureOutput(t, func() {
+				result = f.ShouldProcess(tt.repoName)
+			})
+
+			if result != tt.expected {
+				t.Errorf("ShouldProcess(%q) = %t, expected %t", tt.repoName, result, tt.expected)
+			}
+			if !strings.Contains(output, "DEBUG") || !strings.Contains(output, tt.expectedLog) {
+				t.Errorf("expected debug log containing %q, got:\n%s", tt.expectedLog, output)
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	f, err := NewFilter([]string{"service-old"}, []string{"^service-"}, []string{"-legacy$"})
+	if err != nil {
+		t.Fatalf("NewFilter returned error: %v", err)
+	}
+
+	tests := map[string]string{
+		"service-old":    ReasonSkipList,
+		"service-legacy": ReasonExcludePattern,
+		"frontend":       ReasonIncludeMismatch,
+		"service-api":    "",
+	}
+	for repoName, expected := range tests {
+		if got := f.Explain(repoName); got != expected {
+			t.Errorf("Explain(%q) = %q, expected %q", repoName, got, expected)
+		}
+	}
+}
+
+func TestWithExtraSkipRepos(t *testing.T) {
+	base, err := NewFilter([]string{"old-project"}, []string{"^service-"}, nil)
+	if err != nil {
+		t.Fatalf("NewFilter returned error: %v", err)
+	}
+
+	extended := base.WithExtraSkipRepos("service-legacy")
+	if extended.ShouldProcess("service-legacy") || extended.ShouldProcess("old-project") {
+		t.Error("expected the clone to skip the base and the extra repositories")
+	}
+	if !extended.ShouldProcess("service-api") || extended.ShouldProcess("frontend") {
+		t.Error("expected the clone to keep the include pattern")
+	}
+	if !base.ShouldProcess("service-legacy") {
+		t.Error("the base filter was changed by WithExtraSkipRepos")
+	}
+}
+
+func TestNewFilterInvalidPattern(t *testing.T) {
+	if _, err := NewFilter(nil, []string{"^ok-", "([a-z"}, nil); err == nil {
+		t.Error("expected an error for an invalid include pattern")
+	}
+	if _, err := NewFilter(nil, nil, []string{"*invalid"}); err == nil {
+		t.Error("expected an error for an invalid exclude pattern")
+	}
+}
+
+func TestSerializeFilter(t *testing.T) {
+	original, err := NewFilter([]string{"service-old", "old-project"}, []string{"^service-"}, []string{"-legacy$"})
+	if err != nil {
+		t.Fatalf("NewFilter returned error: %v", err)
+	}
+
+	data, err := original.Serialize()
+	if err != nil {
+		t.Fatalf("Serialize returned error: %v", err)
+	}
+	restored, err := DeserializeFilter(data)
+	if err != nil {
+		t.Fatalf("DeserializeFilter returned error: %v", err)
+	}
+
+	for _, repoName := range []string{"service-old", "old-project", "service-legacy", "frontend", "service-api"} {
+		if got, expected := restored.Explain(repoName), original.Explain(repoName); got != expected {
+			t.Errorf("Explain(%q) = %q after the round trip, expected %q", repoName, got, expected)
+		}
+	}
+	// The skip list is sorted, so the same filter is always serialized the same way
+	if again, err := restored.Serialize(); err != nil || string(again) != string(data) {
+		t.Errorf("expected the restored filter to serialize to %s, got %s (error %v)", data, again, err)
+	}
+}
+
+func TestDeserializeFilterInvalid(t *testing.T) {
+	_, err := DeserializeFilter([]byte(`{"skip_repos":["old-project"],"exclude_patterns":["*invalid"]}`))
+	var filterErr *FilterError
+	if !errors.As(err, &filterErr) || filterErr.Pattern != "*invalid" {
+		t.Errorf("expected a FilterError for the invalid pattern, got %v", err)
+	}
+
+	if _, err := DeserializeFilter([]byte("not json")); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func BenchmarkFilterRepositories(b *testing.B) {
+	f, err := NewFilter(
+		[]string{"service-0001", "service-0500", "legacy-0042"},
+		[]string{`^service-[0-9]{4}(-[a-z]+)?$`, `^lib-[0-9]{4}(-[a-z]+)?$`},
+		[]string{`(-deprecated|-archived)$`, `^legacy-`},
+	)
+	if err != nil {
+		b.Fatalf("NewFilter returned error: %v", err)
+	}
+
+	prefixes := []string{"service", "lib", "legacy", "tool"}
+	suffixes := []string{"", "-api", "-deprecated", "-archived"}
+
+	for _, size := range []int{100, 1000, 10000} {
+		repos := make([]string, size)
+		for i := range repos {
+			repos[i] = fmt.Sprintf("%s-%04d%s", prefixes[i%len(prefixes)], i, suffixes[(i/len(prefixes))%len(suffixes)])
+		}
+
+		b.Run(fmt.Sprintf("repos=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				f.FilterRepositories(repos)
+			}
+		})
+	}
+}
@@ -3,16 +3,43 @@ package getinfo
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 
 	"github.com/aeciopires/updateGit/internal/config"
 	"github.com/aeciopires/updateGit/internal/common"
+	"github.com/spf13/viper"
 )
 
-// PrintLongVersion prints the application version
+// PrintLongVersion prints the application version, operating system, arch, git version
+// and the config file in use
 func PrintLongVersion() {
-	fmt.Printf("Version: %s\n", config.CLIVersion)
+	writeLongVersion(os.Stdout)
+}
+
+// writeLongVersion writes the lines of PrintLongVersion to w
+func writeLongVersion(w io.Writer) {
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" {
+		configFile = "none (defaults, environment variables and flags)"
+	}
+
+	gitVersion, err := GetGitVersion()
+	if err != nil {
+		gitVersion = fmt.Sprintf("not available (%v)", err)
+	}
+
+	fmt.Fprintf(w, "Version: %s\n", config.CLIVersion)
+	fmt.Fprintf(w, "Operating system: %s\n", runtime.GOOS)
+	fmt.Fprintf(w, "System Arch: %s\n", GetSystemArch())
+	fmt.Fprintf(w, "Git: %s\n", gitVersion)
+	fmt.Fprintf(w, "Config: %s\n", configFile)
+}
+
+// GetGitVersion returns the version number of the installed git binary, e.g. "2.39.1"
+func GetGitVersion() (string, error) {
+	return common.CommandVersion("git")
 }
 
 // PrintShortVersion prints only number of the application version
@@ -32,19 +59,24 @@ func ShowOperatingSystem() {
 	}
 }
 
-// CheckOperatingSystem check if operating system is supported
-func CheckOperatingSystem() {
-	osName := runtime.GOOS
+// CheckOperatingSystem check if operating system is supported.
+// The caller passes runtime.GOOS, so different platforms can be tested.
+func CheckOperatingSystem(osName string) error {
 	switch osName {
 	case "darwin", "linux":
 		common.Logger("debug", "Operating system: %s", osName)
+		return nil
 	default:
-		common.Logger("fatal", "%s is not supported.", osName)
+		return fmt.Errorf("%s is not supported", osName)
 	}
 }
 
+// GetSystemArch returns the system arch
+func GetSystemArch() string {
+	return runtime.GOARCH
+}
+
 // ShowSystemArch prints the system arch
 func ShowSystemArch() {
-	arch := runtime.GOARCH
-	fmt.Println("System Arch:", arch)
+	fmt.Println("System Arch:", GetSystemArch())
 }
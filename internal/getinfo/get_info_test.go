@@ -0,0 +1,103 @@
+package getinfo
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aeciopires/updateGit/internal/config"
+)
+
+func TestGetSystemArch(t *testing.T) {
+	knownArchs := map[string]bool{
+		"386": true, "amd64": true, "arm": true, "arm64": true, "loong64": true,
+		"mips": true, "mipsle": true, "mips64": true, "mips64le": true,
+		"ppc64": true, "ppc64le": true, "riscv64": true, "s390x": true, "wasm": true,
+	}
+
+	arch := GetSystemArch()
+	if arch == "" {
+		t.Fatal("expected a non-empty arch")
+	}
+	if !knownArchs[arch] {
+		t.Errorf("unknown GOARCH value: %s", arch)
+	}
+}
+
+func TestCheckOperatingSystem(t *testing.T) {
+	tests := []struct {
+		osName    string
+		supported bool
+	}{
+		{osName: "darwin", supported: true},
+		{osName: "linux", supported: true},
+		{osName: "windows", supported: false},
+		{osName: "freebsd", supported: false},
+		{osName: "", supported: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.osName, func(t *testing.T) {
+			err := CheckOperatingSystem(tt.osName)
+			if tt.supported && err != nil {
+				t.Errorf("expected %q to be supported, got error: %v", tt.osName, err)
+			}
+			if !tt.supported && err == nil {
+				t.Errorf("expected an error for %q", tt.osName)
+			}
+		})
+	}
+}
+
+func TestWriteLongVersion(t *testing.T) {
+	var output strings.Builder
+	writeLongVersion(&output)
+
+	for _, label := range []string{"Version: ", "Operating system: ", "System Arch: ", "Git: ", "Config: "} {
+		if !strings.Contains(output.String(), "\n"+label) && !strings.HasPrefix(output.String(), label) {
+			t.Errorf("expected a line starting with %q, got:\n%s", label, output.String())
+		}
+	}
+}
+
+func TestGetGitVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake git command is a shell script")
+	}
+
+	tests := []struct {
+		name     string
+		output   string
+		expected string
+		wantErr  bool
+	}{
+		{name: "release", output: "git version 2.39.1", expected: "2.39.1"},
+		{name: "apple git", output: "git version 2.39.3 (Apple Git-146)", expected: "2.39.3"},
+		{name: "windows build", output: "git version 2.45.windows.1", expected: "2.45"},
+		{name: "no version", output: "git", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binDir := t.TempDir()
+			fakeGit := "#!/bin/sh\necho '" + tt.output + "'\n"
+			if err := os.WriteFile(filepath.Join(binDir, "git"), []byte(fakeGit), config.PermissionBinary); err != nil {
+				t.Fatalf("could not write fake git: %v", err)
+			}
+			t.Setenv("PATH", binDir)
+
+			version, err := GetGitVersion()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got version %q", version)
+				}
+				return
+			}
+			if err != nil || version != tt.expected {
+				t.Errorf("GetGitVersion() = %q, %v, expected %q", version, err, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aeciopires/updateGit/internal/common"
+	"github.com/aeciopires/updateGit/internal/config"
+)
+
+// gitConfigEntry is a git configuration key passed to a single git command
+type gitConfigEntry struct {
+	Key   string
+	Value string
+}
+
+// newGitCommand creates a git command executed in repoPath (the current directory when empty)
+// with the git configuration managed by updateGit
+func newGitCommand(repoPath string, args ...string) *exec.Cmd {
+	return newGitCommandWithConfig(repoPath, nil, args...)
+}
+
+// newGitCommandWithConfig works like newGitCommand, adding the entries only to this command
+func newGitCommandWithConfig(repoPath string, entries []gitConfigEntry, args ...string) *exec.Cmd {
+	return newGitCommandWithConfigContext(context.Background(), repoPath, entries, args...)
+}
+
+// newGitCommandWithConfigContext works like newGitCommandWithConfig, killing the command when ctx is done
+func newGitCommandWithConfigContext(ctx context.Context, repoPath string, entries []gitConfigEntry, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repoPath
+	setGitEnv(cmd, entries...)
+	return cmd
+}
+
+// CommandFactory creates the command name with args executed in dir.
+// UpdateConfig uses it to run git pull, so tests can replace git with a fake command
+type CommandFactory func(dir, name string, args ...string) *exec.Cmd
+
+// DefaultCommandFactory creates the command with exec.Command
+func DefaultCommandFactory(dir, name string, args ...string) *exec.Cmd {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	return cmd
+}
+
+// newGitCommandFromFactory works like newGitCommandWithConfig, creating the command with factory
+func newGitCommandFromFactory(factory CommandFactory, repoPath string, entries []gitConfigEntry, args ...string) *exec.Cmd {
+	cmd := factory(repoPath, "git", args...)
+	setGitEnv(cmd, entries...)
+	return cmd
+}
+
+// runCommandContext runs cmd and returns its combined stdout and stderr. The command is killed when ctx is done.
+// It works with commands not created by exec.CommandContext, e.g. the ones of a CommandFactory
+func runCommandContext(ctx context.Context, cmd *exec.Cmd) (string, error) {
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return output.String(), err
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		err := <-done
+		return output.String(), err
+	}
+}
+
+// ConfigOptions holds the git configuration keys passed to every git command run by this package.
+// Zero values keep the git configuration.
+type ConfigOptions struct {
+	// MaxPackSize is the memory in MiB used by git to handle packs (pack.windowMemory)
+	MaxPackSize int
+	// HTTPLowSpeedLimit aborts HTTP transfers slower than this value in bytes per second (http.lowSpeedLimit)
+	HTTPLowSpeedLimit int
+	// HTTPLowSpeedTime is the number of seconds below HTTPLowSpeedLimit before the transfer is aborted (http.lowSpeedTime)
+	HTTPLowSpeedTime int
+	// SafeDirectory is a repository owned by another user that git can use (safe.directory)
+	SafeDirectory string
+	// CredentialHelper is added to the credential helpers of the git configuration (credential.helper)
+	CredentialHelper string
+}
+
+var (
+	// configOptions are the options of SetConfigOptions
+	configOptions   ConfigOptions
+	configOptionsMu sync.RWMutex
+)
+
+// SetConfigOptions sets the git configuration keys passed to the git commands run by this package,
+// e.g. by GetCurrentBranch or CloneRepository. An update with UpdateConfig.GitConfig replaces them until it finishes
+func SetConfigOptions(options ConfigOptions) {
+	configOptionsMu.Lock()
+	defer configOptionsMu.Unlock()
+	configOptions = options
+}
+
+// currentConfigOptions returns the options of SetConfigOptions
+func currentConfigOptions() ConfigOptions {
+	configOptionsMu.RLock()
+	defer configOptionsMu.RUnlock()
+	return configOptions
+}
+
+// entries returns the git configuration keys of the options
+func (options ConfigOptions) entries() []gitConfigEntry {
+	var entries []gitConfigEntry
+
+	if options.MaxPackSize > 0 {
+		entries = append(entries, gitConfigEntry{Key: "pack.windowMemory", Value: fmt.Sprintf("%dm", options.MaxPackSize)})
+	}
+	if options.HTTPLowSpeedLimit > 0 {
+		entries = append(entries, gitConfigEntry{Key: "http.lowSpeedLimit", Value: strconv.Itoa(options.HTTPLowSpeedLimit)})
+	}
+	if options.HTTPLowSpeedTime > 0 {
+		entries = append(entries, gitConfigEntry{Key: "http.lowSpeedTime", Value: strconv.Itoa(options.HTTPLowSpeedTime)})
+	}
+	if options.SafeDirectory != "" {
+		entries = append(entries, gitConfigEntry{Key: "safe.directory", Value: options.SafeDirectory})
+	}
+	// The helper is added to the ones of the git configuration, which are asked first
+	if options.CredentialHelper != "" {
+		entries = append(entries, gitConfigEntry{Key: "credential.helper", Value: options.CredentialHelper})
+	}
+
+	return entries
+}
+
+// CommitIdentity is the author and committer of the commits and tags created by updateGit.
+// Empty fields keep user.name and user.email of the git configuration.
+type CommitIdentity struct {
+	Name  string
+	Email string
+}
+
+// SigningOptions holds the GPG signing of the merge commits created by git pull
+type SigningOptions struct {
+	// Enabled GPG-signs the merge commits
+	Enabled bool
+	// KeyID is the signing key. Empty uses the key of the git configuration
+	KeyID string
+}
+
+// ConfigEnvKeys returns the git configuration keys passed to git commands with the GIT_CONFIG_*
+// environment variables by options, identity and signing.
+// They require git >= config.GitConfigEnvMinVersion.
+func ConfigEnvKeys(options ConfigOptions, identity CommitIdentity, signing SigningOptions) []string {
+	var keys []string
+	for _, entry := range append(options.entries(), commitConfigEntries(identity, signing)...) {
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
+
+// CheckConfigEnvSupport returns an error if the git binary is too old to receive the keys of ConfigEnvKeys
+func CheckConfigEnvSupport(options ConfigOptions, identity CommitIdentity, signing SigningOptions) error {
+	keys := ConfigEnvKeys(options, identity, signing)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if err := common.CheckMinimumVersion("git", config.GitConfigEnvMinVersion); err != nil {
+		return fmt.Errorf("%w. It is required by the git settings: %s", err, strings.Join(keys, ", "))
+	}
+	return nil
+}
+
+// commitConfigEntries returns the git configuration keys of commands that can create commits:
+// the keys of identity and, when signing is enabled, the signing keys
+func commitConfigEntries(identity CommitIdentity, signing SigningOptions) []gitConfigEntry {
+	var entries []gitConfigEntry
+
+	if identity.Name != "" {
+		entries = append(entries, gitConfigEntry{Key: "user.name", Value: identity.Name})
+	}
+	if identity.Email != "" {
+		entries = append(entries, gitConfigEntry{Key: "user.email", Value: identity.Email})
+	}
+
+	return append(entries, signingConfigEntries(signing)...)
+}
+
+// signingConfigEntries returns the git configuration keys to GPG-sign the commits created by git
+// when signing is enabled
+func signingConfigEntries(signing SigningOptions) []gitConfigEntry {
+	if !signing.Enabled {
+		return nil
+	}
+
+	entries := []gitConfigEntry{{Key: "commit.gpgSign", Value: "true"}}
+	if signing.KeyID != "" {
+		entries = append(entries, gitConfigEntry{Key: "user.signingKey", Value: signing.KeyID})
+	}
+	return entries
+}
+
+// setGitEnv passes the git configuration keys to cmd using the GIT_CONFIG_COUNT,
+// GIT_CONFIG_KEY_<n> and GIT_CONFIG_VALUE_<n> environment variables (git >= 2.31).
+// Unlike "git -c", it works for every subcommand without changing the arguments.
+// Keys already defined in the environment by the user are preserved.
+func setGitEnv(cmd *exec.Cmd, extraEntries ...gitConfigEntry) {
+	entries := append(currentConfigOptions().entries(), extraEntries...)
+	if len(entries) == 0 {
+		return
+	}
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+
+	count, err := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	if err != nil || count < 0 {
+		count = 0
+	}
+
+	for _, entry := range entries {
+		common.Logger("debug", "Setting git configuration. key=%s value=%s", entry.Key, entry.Value)
+		cmd.Env = append(cmd.Env,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", count, entry.Key),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", count, entry.Value),
+		)
+		count++
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("GIT_CONFIG_COUNT=%d", count))
+}
@@ -3,25 +3,105 @@
 package git
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aeciopires/updateGit/internal/common"
 	"github.com/aeciopires/updateGit/internal/backup"
-	"github.com/aeciopires/updateGit/internal/filter"
+	"github.com/aeciopires/updateGit/internal/metrics"
 )
 
+// Default timeouts of the update
+const (
+	// DefaultRepoTimeoutSec is the timeout of the update of each repository in seconds
+	DefaultRepoTimeoutSec = 60
+	// DefaultTotalTimeoutSec is the timeout of the update of all repositories in seconds
+	DefaultTotalTimeoutSec = 1800
+)
+
+// RepositoryFilter decides which repositories are updated. It is implemented by filter.Filter
+type RepositoryFilter interface {
+	// Match reports whether the repository must be updated
+	Match(repoName string) bool
+	// Explain returns the reason why the repository is not updated, or an empty string when it is
+	Explain(repoName string) string
+	// GetStats returns information about the filter to be logged
+	GetStats() map[string]interface{}
+}
+
+// BackupProvider creates the backup of a repository before its update. It is implemented by backup.BackupManager
+type BackupProvider interface {
+	// CreateBackup creates the backup of the repository in repoPath
+	CreateBackup(ctx context.Context, repoPath, repoName string) (*backup.BackupInfo, error)
+	// GetBackupStats returns information about the backups to be logged
+	GetBackupStats() map[string]interface{}
+}
+
 // UpdateConfig holds configuration for updating repositories.
+// Use NewUpdateConfig to create it with the default values.
 type UpdateConfig struct {
 	BaseDir       string
 	Parallel      ParallelUpdateConfig
+	TotalTimeout  time.Duration
 	BackupEnabled bool
-	BackupManager *backup.BackupManager
-	Filter        *filter.Filter
+	BackupManager BackupProvider
+	Filter        RepositoryFilter
+	// CommitMessageTemplate replaces the message of merge commits created by git pull.
+	// It is a text/template executed with the Repository, e.g. "Sync {{.Name}} ({{.CurrentBranch}})"
+	CommitMessageTemplate string
+	// ExtraPullArgs are appended to the git pull command line
+	ExtraPullArgs []string
+	// Identity is the author and committer of the merge commits, stash entries and tags created by updateGit
+	Identity CommitIdentity
+	// Signing GPG-signs the merge commits created by git pull when Signing.Enabled is true
+	Signing SigningOptions
+	// PullStrategy adds --no-rebase, --rebase or --ff-only to git pull. Empty keeps the git configuration
+	PullStrategy PullStrategy
+	// FetchDepth limits the history downloaded by the fetch executed before the pull. 0 means not limited
+	FetchDepth int
+	// Tag is created after each successful pull when Tag.Name is not empty
+	Tag TagOptions
+	// Submodules are updated after each successful pull when Submodules.Enabled is true
+	Submodules SubmoduleOptions
+	// PruneWorktrees runs git worktree prune after each successful pull
+	PruneWorktrees bool
+	// PullOnlyIfBehind fetches the upstream and skips the pull when it has no new commits
+	PullOnlyIfBehind bool
+	// AutoStash stashes the uncommitted changes before the pull and restores them after it
+	AutoStash bool
+	// PostPullDiffStat prints and records the diff stat of the commits added by each pull
+	PostPullDiffStat bool
+	// FetchAllRemotes fetches every remote of the repository before the pull
+	FetchAllRemotes bool
+	// FetchBeforePull runs git fetch --all before the pull, with --prune when FetchPrune is true
+	FetchBeforePull bool
+	// FetchPrune removes the remote-tracking branches deleted from the remotes in the fetch of FetchAllRemotes and FetchBeforePull
+	FetchPrune bool
+	// OnRepoStart is called before the update of each repository, when it is not nil.
+	// In parallel updates it is called from several goroutines at the same time
+	OnRepoStart func(repo Repository)
+	// OnRepoComplete is called with the result of each repository after its update, when it is not nil.
+	// Like OnRepoStart, it must be safe for concurrent use in parallel updates
+	OnRepoComplete func(repo Repository, result RepoResult)
+	// DryRun finds and filters the repositories, but skips the backup, pull and post-pull steps
+	DryRun bool
+	// CommandFactory creates the git pull command. DefaultCommandFactory is used when it is nil
+	CommandFactory CommandFactory
+	// GitConfig replaces the options of SetConfigOptions during the update when it is not nil
+	GitConfig *ConfigOptions
+	// MaxDepth is the number of levels of directories below BaseDir scanned for repositories. 0 means unlimited
+	MaxDepth int
+	// IncludeSubmoduleRepos updates the repositories that are submodules of a BaseDir repository
+	IncludeSubmoduleRepos bool
 }
 
 // ParallelUpdateConfig holds parallel update settings.
@@ -37,6 +117,63 @@ type Repository struct {
 	Name          string
 	CurrentBranch string
 	IsValid       bool
+	// Tags pointing to HEAD
+	Tags []string
+}
+
+// Status values of a RepoResult
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	// StatusUpToDate means the pull was skipped because the upstream had no new commits
+	StatusUpToDate = "already-up-to-date"
+	// StatusDryRun means the repository would be updated, but nothing was executed
+	StatusDryRun = "dry-run"
+)
+
+// RepoResult holds the outcome of updating a single repository
+type RepoResult struct {
+	Repository string
+	Path       string
+	Branch     string
+	Status     string
+	Error      string
+	Duration   time.Duration
+	// StaleBranches are local branches whose upstream branch was deleted from the remote
+	StaleBranches []string
+	// DiffStat is the output of git diff --stat of the commits added by the pull
+	DiffStat string
+}
+
+// UpdateSummary holds the results of an update run
+type UpdateSummary struct {
+	Total   int
+	Success int
+	Failed  int
+	// StaleBranches is the number of stale tracking branches in all repositories
+	StaleBranches int
+	// UpToDate is the number of repositories not pulled because they were up to date. They are also counted in Success
+	UpToDate int
+	Results  []RepoResult
+	// Skipped are the repositories found in the base directory but excluded by the filter
+	Skipped []SkippedRepo
+}
+
+// SkippedRepo is a repository excluded by the filter and the reason
+type SkippedRepo struct {
+	Repository string
+	Path       string
+	Reason     string
+}
+
+// CloneOptions holds the options used by CloneRepository
+type CloneOptions struct {
+	// Depth creates a shallow clone with the given number of commits. 0 means full history.
+	Depth int
+	// SingleBranch clones only one branch: Branch if informed, otherwise the default branch.
+	SingleBranch bool
+	// Branch is checked out after the clone instead of the default branch of the remote.
+	Branch string
 }
 
 // GitError represents a git operation error
@@ -50,22 +187,116 @@ func (e *GitError) Error() string {
 	return fmt.Sprintf("git %s failed for repository '%s': %v", e.Operation, e.Repository, e.Err)
 }
 
-// IsGitRepository checks if a directory contains a git repository
+// Unwrap returns the cause of the error, so errors.Is detects e.g. context.DeadlineExceeded
+func (e *GitError) Unwrap() error {
+	return e.Err
+}
+
+// UpdateError is returned when the update of one or more repositories failed.
+// Summary has the result of all repositories, including the successful ones.
+type UpdateError struct {
+	Summary *UpdateSummary
+}
+
+func (e *UpdateError) Error() string {
+	return fmt.Sprintf("update completed with %d errors out of %d repositories", e.Summary.Failed, e.Summary.Total)
+}
+
+// IsGitRepository checks if a directory contains a git repository.
+// Besides a .git directory, a .git file pointing to the git dir (used by worktrees
+// and submodules) is also accepted.
 func IsGitRepository(path string) bool {
 	gitDir := filepath.Join(path, ".git")
-	if info, err := os.Stat(gitDir); err == nil && info.IsDir() {
+	info, err := os.Stat(gitDir)
+	if err == nil && info.IsDir() {
 		common.Logger("debug", "Found git repository. repository=%s", path)
 		return true
 	}
 
+	if err == nil && info.Mode().IsRegular() {
+		content, readErr := os.ReadFile(gitDir)
+		if readErr == nil && strings.HasPrefix(string(content), "gitdir:") {
+			common.Logger("debug", "Found git repository (linked by .git file). repository=%s", path)
+			return true
+		}
+	}
+
 	common.Logger("debug", "Not a git repository. path=%s", path)
 	return false
 }
 
+// IsSubmoduleRepository checks if the repository is a submodule of the repository in its parent directory,
+// that is, the parent is a git repository whose .gitmodules has a submodule with the path of the repository
+func IsSubmoduleRepository(repoPath string) bool {
+	absPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return false
+	}
+	parent := filepath.Dir(absPath)
+
+	gitModules := filepath.Join(parent, ".gitmodules")
+	if _, err := os.Stat(gitModules); err != nil || !IsGitRepository(parent) {
+		return false
+	}
+
+	cmd := newGitCommand(parent, "config", "--file", ".gitmodules", "--get-regexp", `^submodule\..*\.path$`)
+	output, err := cmd.Output()
+	if err != nil {
+		common.Logger("debug", "Could not read submodule paths. file=%s error=%v", gitModules, err)
+		return false
+	}
+
+	// Each line is "submodule.<name>.path <path>"
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		_, path, found := strings.Cut(line, " ")
+		if found && filepath.Join(parent, filepath.FromSlash(path)) == absPath {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetRepoTopLevel returns the root directory of the repository containing path
+func GetRepoTopLevel(path string) (string, error) {
+	cmd := newGitCommand(path, "rev-parse", "--show-toplevel")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", &GitError{
+			Repository: path,
+			Operation:  "rev-parse --show-toplevel",
+			Err:        err,
+		}
+	}
+
+	return filepath.Clean(filepath.FromSlash(strings.TrimSpace(string(output)))), nil
+}
+
+// resolveRepoRoot returns the root directory of the repository in repoPath. repoPath is kept
+// when it is already the root, even if git reports it with the symbolic links resolved.
+func resolveRepoRoot(repoPath string) string {
+	topLevel, err := GetRepoTopLevel(repoPath)
+	if err != nil {
+		common.Logger("debug", "Could not resolve repository root, using the directory. repository=%s error=%v", repoPath, err)
+		return repoPath
+	}
+
+	resolved, err := filepath.EvalSymlinks(repoPath)
+	if err != nil {
+		resolved = repoPath
+	}
+	if absResolved, err := filepath.Abs(resolved); err == nil && absResolved == topLevel {
+		return repoPath
+	}
+
+	common.Logger("debug", "Repository path normalized to its root. path=%s root=%s", repoPath, topLevel)
+	return topLevel
+}
+
 // GetCurrentBranch returns the current branch name for a repository
 func GetCurrentBranch(repoPath string) (string, error) {
-	cmd := exec.Command("git", "symbolic-ref", "HEAD")
-	cmd.Dir = repoPath
+	cmd := newGitCommand(repoPath, "symbolic-ref", "HEAD")
 
 	output, err := cmd.Output()
 	if err != nil {
@@ -90,8 +321,7 @@ func GetCurrentBranch(repoPath string) (string, error) {
 
 // GetBranches returns all local branches for a repository
 func GetBranches(repoPath string) (string, error) {
-	cmd := exec.Command("git", "branch")
-	cmd.Dir = repoPath
+	cmd := newGitCommand(repoPath, "branch")
 
 	output, err := cmd.Output()
 	if err != nil {
@@ -105,136 +335,971 @@ func GetBranches(repoPath string) (string, error) {
 	return string(output), nil
 }
 
-// PullRepository executes git pull on a repository
-func PullRepository(repoPath string) error {
-	common.Logger("info", "Executing git pull. repository=%s", repoPath)
+// GetTagsAtHead returns the tags pointing to the commit of HEAD
+func GetTagsAtHead(repoPath string) ([]string, error) {
+	cmd := newGitCommand(repoPath, "tag", "--points-at", "HEAD")
 
-	cmd := exec.Command("git", "pull")
-	cmd.Dir = repoPath
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, &GitError{
+			Repository: repoPath,
+			Operation:  "tag",
+			Err:        err,
+		}
+	}
 
-	if err := cmd.Run(); err != nil {
-		return &GitError{
+	return strings.Fields(string(output)), nil
+}
+
+// staleBranchPattern matches a line of "git branch -vv" whose upstream branch is gone, e.g.
+// "* feature  1a2b3c4 [origin/feature: gone] Commit message"
+var staleBranchPattern = regexp.MustCompile(`^[*+ ] (\S+)\s+[0-9a-f]+ \[[^\]]+: gone\]`)
+
+// GetStaleTrackingBranches returns the local branches whose upstream branch was deleted from the remote.
+// The remote-tracking branches are only removed by a fetch with --prune (or fetch.prune=true).
+func GetStaleTrackingBranches(repoPath string) ([]string, error) {
+	cmd := newGitCommand(repoPath, "branch", "-vv")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, &GitError{
 			Repository: repoPath,
-			Operation:  "pull",
+			Operation:  "branch -vv",
 			Err:        err,
 		}
 	}
 
-	common.Logger("info", "Git pull completed successfully. repository=%s", repoPath)
+	return parseStaleTrackingBranches(string(output)), nil
+}
+
+// parseStaleTrackingBranches returns the branches of the "git branch -vv" output whose upstream is gone
+func parseStaleTrackingBranches(output string) []string {
+	var staleBranches []string
+	for _, line := range strings.Split(output, "\n") {
+		if matches := staleBranchPattern.FindStringSubmatch(line); matches != nil {
+			staleBranches = append(staleBranches, matches[1])
+		}
+	}
+	return staleBranches
+}
+
+// DeleteBranch deletes a local branch with "git branch -d", which refuses to delete a branch that is not fully merged
+func DeleteBranch(repoPath, branch string) error {
+	cmd := newGitCommand(repoPath, "branch", "-d", branch)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return &GitError{
+			Repository: repoPath,
+			Operation:  "branch -d",
+			Err:        fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output))),
+		}
+	}
 	return nil
 }
 
-// FindRepositories discovers all git repositories in a base directory
-func FindRepositories(baseDir string) ([]Repository, error) {
-	common.Logger("info", "Scanning for git repositories. baseDir=%s", baseDir)
+// FetchRepository downloads the objects and refs of all remotes with "git fetch --all", without merging them.
+// With prune, remote-tracking branches deleted from the remotes are removed.
+func FetchRepository(repoPath string, prune bool) error {
+	args := []string{"fetch", "--all"}
+	if prune {
+		args = append(args, "--prune")
+	}
 
-	var repositories []Repository
+	cmd := newGitCommand(repoPath, args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return &GitError{
+			Repository: repoPath,
+			Operation:  "fetch --all",
+			Err:        fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output))),
+		}
+	}
+	if len(output) > 0 {
+		common.Logger("debug", "Git fetch output. repository=%s\n%s", repoPath, strings.TrimRight(string(output), "\n"))
+	}
+
+	return nil
+}
+
+// fetchUpstream downloads the objects and refs of the upstream of the current branch without merging them.
+// A depth greater than 0 limits the history downloaded.
+func fetchUpstream(repoPath string, depth int) error {
+	return FetchRemote(repoPath, "", depth, false)
+}
 
-	entries, err := os.ReadDir(baseDir)
+// FetchRemote works like fetchUpstream, but fetches the remote with the given name.
+// An empty remote uses the default of git fetch. With prune, remote-tracking branches
+// deleted from the remote are removed.
+func FetchRemote(repoPath, remote string, depth int, prune bool) error {
+	args := []string{"fetch"}
+	if depth > 0 {
+		args = append(args, fmt.Sprintf("--depth=%d", depth))
+	}
+	if prune {
+		args = append(args, "--prune")
+	}
+	if remote != "" {
+		args = append(args, remote)
+	}
+
+	cmd := newGitCommand(repoPath, args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return &GitError{
+			Repository: repoPath,
+			Operation:  "fetch",
+			Err:        fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output))),
+		}
+	}
+	if len(output) > 0 {
+		common.Logger("debug", "Git fetch output. repository=%s remote=%s\n%s", repoPath, remote, strings.TrimRight(string(output), "\n"))
+	}
+
+	return nil
+}
+
+// GetRemotes returns the names of the remotes configured in the repository
+func GetRemotes(repoPath string) ([]string, error) {
+	cmd := newGitCommand(repoPath, "remote")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, &GitError{
+			Repository: repoPath,
+			Operation:  "remote",
+			Err:        err,
+		}
+	}
+
+	return strings.Fields(string(output)), nil
+}
+
+// GetRemoteURL returns the URL of the remote, as configured in the repository
+func GetRemoteURL(repoPath, remote string) (string, error) {
+	cmd := newGitCommand(repoPath, "remote", "get-url", remote)
+
+	output, err := cmd.Output()
 	if err != nil {
-		common.Logger("fatal", "Failed to read directory '%s': %v", baseDir, err)
+		return "", &GitError{
+			Repository: repoPath,
+			Operation:  "remote get-url",
+			Err:        err,
+		}
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// HasHTTPSRemote checks if any remote of the repository uses an HTTP or HTTPS URL, which may need credentials
+func HasHTTPSRemote(repoPath string) bool {
+	remotes, err := GetRemotes(repoPath)
+	if err != nil {
+		return false
+	}
+
+	for _, remote := range remotes {
+		url, err := GetRemoteURL(repoPath, remote)
+		if err != nil {
+			continue
+		}
+		url = strings.ToLower(url)
+		if strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") {
+			return true
+		}
 	}
+	return false
+}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
+// CredentialHelperConfigured checks if git has a credential helper for the repository,
+// from the git configuration or from --git-credential-helper
+func CredentialHelperConfigured(repoPath string) bool {
+	cmd := newGitCommand(repoPath, "config", "--get-all", "credential.helper")
+	output, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(output)) != ""
+}
+
+// fetchAllRemotes fetches each remote of the repository in order. Failures are logged
+// and do not stop the fetch of the other remotes.
+func fetchAllRemotes(repo Repository, depth int, prune bool) {
+	remotes, err := GetRemotes(repo.Path)
+	if err != nil {
+		common.Logger("warning", "Could not list remotes. repository=%s error=%v", repo.Name, err)
+		return
+	}
+
+	for _, remote := range remotes {
+		if err := FetchRemote(repo.Path, remote, depth, prune); err != nil {
+			common.Logger("warning", "Could not fetch remote. repository=%s remote=%s error=%v", repo.Name, remote, err)
 			continue
 		}
+		common.Logger("info", "Remote fetched. repository=%s remote=%s", repo.Name, remote)
+	}
+}
+
+// GetCommitsBehind fetches the upstream with fetchUpstream and returns the number of upstream commits
+// missing in HEAD. It fails if the current branch has no upstream.
+func GetCommitsBehind(repoPath string, fetchDepth int) (int, error) {
+	if err := fetchUpstream(repoPath, fetchDepth); err != nil {
+		return 0, err
+	}
+	return CountCommitsBehind(repoPath)
+}
+
+// CountCommitsBehind returns the number of upstream commits missing in HEAD without fetching,
+// so the upstream is as recent as the last fetch. It fails if the current branch has no upstream.
+func CountCommitsBehind(repoPath string) (int, error) {
+	_, behind, err := CountCommitsAheadBehind(repoPath)
+	return behind, err
+}
+
+// CountCommitsAheadBehind returns the number of local commits missing in the upstream and of upstream
+// commits missing in HEAD, without fetching. It fails if the current branch has no upstream.
+func CountCommitsAheadBehind(repoPath string) (ahead, behind int, err error) {
+	cmd := newGitCommand(repoPath, "rev-list", "--left-right", "--count", "HEAD...@{u}")
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, 0, &GitError{
+			Repository: repoPath,
+			Operation:  "rev-list",
+			Err:        err,
+		}
+	}
+
+	// The output is the number of commits ahead and behind, separated by a tab
+	counts := strings.Fields(string(output))
+	if len(counts) != 2 {
+		return 0, 0, &GitError{
+			Repository: repoPath,
+			Operation:  "rev-list",
+			Err:        fmt.Errorf("unexpected output: %q", strings.TrimSpace(string(output))),
+		}
+	}
+	if ahead, err = strconv.Atoi(counts[0]); err == nil {
+		behind, err = strconv.Atoi(counts[1])
+	}
+	if err != nil {
+		return 0, 0, &GitError{
+			Repository: repoPath,
+			Operation:  "rev-list",
+			Err:        err,
+		}
+	}
+	return ahead, behind, nil
+}
+
+// GetDiffStat returns the output of git diff --stat between the commits from and to
+func GetDiffStat(repoPath, from, to string) (string, error) {
+	cmd := newGitCommand(repoPath, "diff", "--stat", from+".."+to)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", &GitError{
+			Repository: repoPath,
+			Operation:  "diff --stat",
+			Err:        err,
+		}
+	}
+
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// postPullDiffStat prints the diff stat of the commits added by the pull, indented under the repository name,
+// and returns it. It returns an empty string when HEAD did not change.
+func postPullDiffStat(repo Repository, headBefore string) string {
+	headAfter, err := GetHeadCommit(repo.Path)
+	if err != nil || headBefore == "" || headAfter == headBefore {
+		return ""
+	}
+
+	diffStat, err := GetDiffStat(repo.Path, headBefore, headAfter)
+	if err != nil {
+		common.Logger("warning", "Could not get diff stat. repository=%s error=%v", repo.Name, err)
+		return ""
+	}
+
+	fmt.Printf("%s:\n", repo.Name)
+	for _, line := range strings.Split(diffStat, "\n") {
+		fmt.Printf("    %s\n", strings.TrimSpace(line))
+	}
+	return diffStat
+}
+
+// PruneWorktrees removes the administrative files of worktrees whose directory was deleted
+// (.git/worktrees/<name>)
+func PruneWorktrees(repoPath string) error {
+	cmd := newGitCommand(repoPath, "worktree", "prune")
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return &GitError{
+			Repository: repoPath,
+			Operation:  "worktree prune",
+			Err:        fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output))),
+		}
+	}
+
+	return nil
+}
+
+// PullStrategy is how git pull integrates the upstream commits in the current branch
+type PullStrategy string
+
+// Pull strategies. An empty PullStrategy keeps the git configuration (pull.rebase and pull.ff)
+const (
+	PullStrategyMerge  PullStrategy = "merge"
+	PullStrategyRebase PullStrategy = "rebase"
+	PullStrategyFFOnly PullStrategy = "ff-only"
+)
+
+// pullArgs returns the git pull arguments of the strategy
+func (s PullStrategy) pullArgs() []string {
+	switch s {
+	case PullStrategyMerge:
+		return []string{"--no-rebase"}
+	case PullStrategyRebase:
+		return []string{"--rebase"}
+	case PullStrategyFFOnly:
+		return []string{"--ff-only"}
+	}
+	return nil
+}
+
+// pullWaitDelay is how long PullRepository waits for the output of the child processes of git
+// (e.g. ssh) after git pull is killed
+const pullWaitDelay = 5 * time.Second
+
+// PullRepository executes git pull on a repository. extraArgs are appended after the pull arguments managed by updateGit
+// It returns the combined stdout and stderr of git, so the output of parallel pulls is not interleaved.
+// The pull is killed when ctx is done, returning a *GitError that wraps ctx.Err() (e.g. context.DeadlineExceeded).
+// Merge commits are created with the identity and signing of the git configuration.
+func PullRepository(ctx context.Context, repoPath string, strategy PullStrategy, extraArgs ...string) (string, error) {
+	return pullRepository(ctx, DefaultCommandFactory, repoPath, strategy, CommitIdentity{}, SigningOptions{}, extraArgs...)
+}
+
+// pullRepository works like PullRepository, creating the git command with factory.
+// The merge commits are created by identity and signed when signing is enabled.
+func pullRepository(ctx context.Context, factory CommandFactory, repoPath string, strategy PullStrategy, identity CommitIdentity, signing SigningOptions, extraArgs ...string) (string, error) {
+	common.Logger("info", "Executing git pull. repository=%s strategy=%s", repoPath, strategy)
+
+	args := append([]string{"pull"}, strategy.pullArgs()...)
+	args = append(args, extraArgs...)
+	common.Logger("debug", "Git pull arguments. repository=%s args=%v", repoPath, args)
+
+	// Signing is only needed when the pull can create a merge commit
+	if signing.Enabled {
+		if MergeCommitPossible(repoPath) {
+			common.Logger("debug", "Merge commit possible, it will be signed. repository=%s", repoPath)
+		} else {
+			signing = SigningOptions{}
+		}
+	}
 
-		repoPath := filepath.Join(baseDir, entry.Name())
+	cmd := newGitCommandFromFactory(factory, repoPath, commitConfigEntries(identity, signing), args...)
+	cmd.Stdin = os.Stdin
+	cmd.WaitDelay = pullWaitDelay
 
-		if IsGitRepository(repoPath) {
-			currentBranch, err := GetCurrentBranch(repoPath)
-			if err != nil {
-				common.Logger("warning", "Could not determine current branch. repository=%s error=%v", repoPath, err)
+	output, err := runCommandContext(ctx, cmd)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return output, &GitError{
+				Repository: repoPath,
+				Operation:  "pull",
+				Err:        fmt.Errorf("git pull was stopped: %w", ctxErr),
 			}
+		}
+		return output, &GitError{
+			Repository: repoPath,
+			Operation:  "pull",
+			Err:        fmt.Errorf("%v: %s", err, strings.TrimSpace(output)),
+		}
+	}
+
+	common.Logger("info", "Git pull completed successfully. repository=%s", repoPath)
+	return output, nil
+}
+
+// MergeCommitPossible checks if git pull can create a merge commit, i.e. HEAD is not an ancestor
+// of the upstream branch because there are local commits.
+// The upstream ref is the one of the last fetch. New remote commits do not change the result,
+// because HEAD stays an ancestor of them unless the remote branch was rewritten.
+// When the check is not possible (e.g. no upstream branch), it assumes a merge commit is possible.
+func MergeCommitPossible(repoPath string) bool {
+	cmd := newGitCommand(repoPath, "merge-base", "--is-ancestor", "HEAD", "@{upstream}")
+	return cmd.Run() != nil
+}
+
+// GetHeadCommit returns the SHA of the commit pointed by HEAD
+func GetHeadCommit(repoPath string) (string, error) {
+	cmd := newGitCommand(repoPath, "rev-parse", "HEAD")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", &GitError{
+			Repository: repoPath,
+			Operation:  "rev-parse",
+			Err:        err,
+		}
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// IsMergeCommit checks if the commit pointed by HEAD has more than one parent
+func IsMergeCommit(repoPath string) bool {
+	cmd := newGitCommand(repoPath, "rev-parse", "--verify", "--quiet", "HEAD^2")
+	return cmd.Run() == nil
+}
+
+// IsDetachedHead checks if HEAD points to a commit instead of a branch
+func IsDetachedHead(repoPath string) bool {
+	cmd := newGitCommand(repoPath, "symbolic-ref", "--quiet", "HEAD")
+	return cmd.Run() != nil
+}
+
+// AmendCommitMessage replaces the message of the commit pointed by HEAD.
+// The new commit is created by identity and signed when signing is enabled.
+func AmendCommitMessage(repoPath, message string, identity CommitIdentity, signing SigningOptions) error {
+	cmd := newGitCommandWithConfig(repoPath, commitConfigEntries(identity, signing), "commit", "--amend", "--no-verify", "-m", message)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return &GitError{
+			Repository: repoPath,
+			Operation:  "commit --amend",
+			Err:        fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output))),
+		}
+	}
+
+	return nil
+}
+
+// RenderCommitMessage executes the commit message template with the repository data
+func RenderCommitMessage(messageTemplate string, repo Repository) (string, error) {
+	return renderRepositoryTemplate("commit message", messageTemplate, repo)
+}
 
-			repo := Repository{
-				Path:          repoPath,
-				Name:          entry.Name(),
-				CurrentBranch: currentBranch,
-				IsValid:       true,
+// applyCommitMessageTemplate amends the merge commit created by git pull with the template message.
+// After a successful pull MERGE_HEAD no longer exists, so a merge is detected when HEAD moved
+// to a commit with two parents.
+func applyCommitMessageTemplate(repo Repository, messageTemplate, headBefore string, identity CommitIdentity, signing SigningOptions) {
+	headAfter, err := GetHeadCommit(repo.Path)
+	if err != nil || headAfter == headBefore || !IsMergeCommit(repo.Path) {
+		common.Logger("debug", "No merge commit created by pull. repository=%s", repo.Name)
+		return
+	}
+
+	message, err := RenderCommitMessage(messageTemplate, repo)
+	if err != nil {
+		common.Logger("error", "Could not create merge commit message. repository=%s error=%v", repo.Name, err)
+		return
+	}
+
+	if err := AmendCommitMessage(repo.Path, message, identity, signing); err != nil {
+		common.Logger("error", "Could not amend merge commit message. repository=%s error=%v", repo.Name, err)
+		return
+	}
+
+	common.Logger("info", "Merge commit message replaced by template. repository=%s", repo.Name)
+}
+
+// CloneRepository executes git clone of repoURL into destination
+func CloneRepository(repoURL, destination string, opts CloneOptions) error {
+	common.Logger("info", "Executing git clone. url=%s destination=%s depth=%d single_branch=%t branch=%s", repoURL, destination, opts.Depth, opts.SingleBranch, opts.Branch)
+
+	args := []string{"clone"}
+	if opts.Depth > 0 {
+		args = append(args, fmt.Sprintf("--depth=%d", opts.Depth))
+	}
+	if opts.SingleBranch {
+		args = append(args, "--single-branch")
+	}
+	if opts.Branch != "" {
+		args = append(args, "--branch", opts.Branch)
+	}
+	args = append(args, repoURL, destination)
+
+	cmd := newGitCommand("", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+
+	if err := cmd.Run(); err != nil {
+		return &GitError{
+			Repository: repoURL,
+			Operation:  "clone",
+			Err:        err,
+		}
+	}
+
+	common.Logger("info", "Git clone completed successfully. repository=%s", destination)
+	return nil
+}
+
+// FindRepositories discovers all git repositories in the directories of a base directory
+func FindRepositories(baseDir string) ([]Repository, error) {
+	return FindRepositoriesRecursive(baseDir, 1, false)
+}
+
+// FindRepositoriesRecursive discovers the git repositories below a base directory, up to maxDepth levels
+// of directories (0 means unlimited). It does not descend into repositories, so nested repositories are not found.
+// The submodules of a base directory that is a repository are skipped, unless includeSubmoduleRepos is true.
+// The name of each repository is its path relative to baseDir, e.g. "work/api".
+func FindRepositoriesRecursive(baseDir string, maxDepth int, includeSubmoduleRepos bool) ([]Repository, error) {
+	common.Logger("info", "Scanning for git repositories. baseDir=%s max_depth=%d", baseDir, maxDepth)
+
+	var repositories []Repository
+	// Paths already added, as different directories may resolve to the same repository root
+	seen := make(map[string]bool)
+
+	err := filepath.WalkDir(baseDir, func(repoPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if repoPath == baseDir {
+				return err
 			}
+			common.Logger("warning", "Could not read directory, skipping it. directory=%s error=%v", repoPath, err)
+			return filepath.SkipDir
+		}
+		if repoPath == baseDir || !entry.IsDir() {
+			return nil
+		}
+		if entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
 
-			repositories = append(repositories, repo)
-			common.Logger("debug", "Repository added to update list. repository=%s branch=%s", repoPath, currentBranch)
-		} else {
+		relPath, err := filepath.Rel(baseDir, repoPath)
+		if err != nil {
+			return err
+		}
+		depth := len(strings.Split(relPath, string(filepath.Separator)))
+		if maxDepth > 0 && depth > maxDepth {
+			return filepath.SkipDir
+		}
+
+		// IsGitRepository does not tell a missing .git from an unreadable directory
+		if _, err := os.Stat(filepath.Join(repoPath, ".git")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			common.Logger("warning", "Could not check if directory is a git repository, skipping it. directory=%s error=%v", repoPath, err)
+			return filepath.SkipDir
+		}
+
+		if !IsGitRepository(repoPath) {
 			common.Logger("debug", "Skipping non-git directory. directory=%s", repoPath)
+			return nil
+		}
+
+		if !includeSubmoduleRepos && IsSubmoduleRepository(repoPath) {
+			common.Logger("debug", "Skipping submodule repository. Use --git-include-submodule-repos to update it. repository=%s", repoPath)
+			return filepath.SkipDir
+		}
+
+		rootPath := resolveRepoRoot(repoPath)
+		if seen[rootPath] {
+			common.Logger("debug", "Repository already in update list. repository=%s", rootPath)
+			return filepath.SkipDir
 		}
+		seen[rootPath] = true
+
+		currentBranch, err := GetCurrentBranch(rootPath)
+		if err != nil {
+			common.Logger("warning", "Could not determine current branch. repository=%s error=%v", rootPath, err)
+		}
+
+		tags, err := GetTagsAtHead(rootPath)
+		if err != nil {
+			common.Logger("warning", "Could not list tags at HEAD. repository=%s error=%v", rootPath, err)
+		}
+
+		repositories = append(repositories, Repository{
+			Path:          rootPath,
+			Name:          filepath.ToSlash(relPath),
+			CurrentBranch: currentBranch,
+			IsValid:       true,
+			Tags:          tags,
+		})
+		common.Logger("debug", "Repository added to update list. repository=%s branch=%s", rootPath, currentBranch)
+
+		// The files of a repository are not scanned for other repositories
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan directory '%s': %w", baseDir, err)
+	}
+
+	// Directories listed in the ignore file of the base directory are not updated
+	ignored, err := loadIgnoreFile(filepath.Join(baseDir, IgnoreFileName))
+	if err != nil {
+		return nil, err
+	}
+	if len(ignored) > 0 {
+		kept := repositories[:0]
+		for _, repo := range repositories {
+			if ignored[repo.Name] {
+				common.Logger("debug", "Repository ignored (in %s). repository=%s", IgnoreFileName, repo.Path)
+				continue
+			}
+			kept = append(kept, repo)
+		}
+		repositories = kept
 	}
 
 	common.Logger("info", "Git repositories found. count=%d", len(repositories))
 	return repositories, nil
 }
 
+// IgnoreFileName is the optional file in the base directory listing directories that are not updated
+const IgnoreFileName = ".updateGitignore"
+
+// loadIgnoreFile reads the directory names listed in the ignore file, one per line.
+// Blank lines and lines starting with '#' are ignored, as well as a trailing '/'.
+// A missing file is not an error and returns an empty map.
+func loadIgnoreFile(path string) (map[string]bool, error) {
+	ignored := make(map[string]bool)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ignored, nil
+		}
+		return nil, fmt.Errorf("failed to read ignore file '%s': %w", path, err)
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		name := strings.TrimSuffix(strings.TrimSpace(line), "/")
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+		ignored[name] = true
+	}
+
+	common.Logger("debug", "Ignore file loaded. path=%s entries=%d", path, len(ignored))
+	return ignored, nil
+}
+
 // UpdateRepositories updates all git repositories in the specified directory
 func UpdateRepositories(baseDir string) error {
 	return UpdateRepositoriesWithConfig(UpdateConfig{BaseDir: baseDir})
 }
 
-// UpdateRepositoriesWithConfig updates repositories with backup/filter/parallel support
+// UpdateRepositoriesWithConfig updates repositories with backup/filter/parallel support.
+// It returns an *UpdateError when any repository fails
 func UpdateRepositoriesWithConfig(cfg UpdateConfig) error {
-	repositories, err := FindRepositories(cfg.BaseDir)
+	_, err := UpdateRepositoriesWithSummary(cfg)
+	return err
+}
+
+// UpdateRepositoriesWithSummary updates repositories like UpdateRepositoriesWithConfig
+// and returns the result of each repository.
+// When any repository fails, the summary is also returned inside an *UpdateError
+func UpdateRepositoriesWithSummary(cfg UpdateConfig) (*UpdateSummary, error) {
+	return UpdateRepositoriesWithSummaryContext(context.Background(), cfg)
+}
+
+// UpdateRepositoriesWithSummaryContext works like UpdateRepositoriesWithSummary. When ctx is done or
+// cfg.TotalTimeout expires, the running pulls are killed and the repositories not started yet fail without being changed
+func UpdateRepositoriesWithSummaryContext(ctx context.Context, cfg UpdateConfig) (*UpdateSummary, error) {
+	summary := &UpdateSummary{}
+
+	if cfg.GitConfig != nil {
+		previousOptions := currentConfigOptions()
+		SetConfigOptions(*cfg.GitConfig)
+		defer SetConfigOptions(previousOptions)
+	}
+
+	if cfg.TotalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.TotalTimeout)
+		defer cancel()
+	}
+
+	repositories, err := FindRepositoriesRecursive(cfg.BaseDir, cfg.MaxDepth, cfg.IncludeSubmoduleRepos)
 	if err != nil {
-		common.Logger("fatal", "Failed to find repositories: %v", err)
+		return summary, fmt.Errorf("failed to find repositories: %w", err)
 	}
 	if len(repositories) == 0 {
 		common.Logger("warning", "No git repositories found. baseDir=%s", cfg.BaseDir)
-		return nil
+		return summary, nil
 	}
 
 	// Apply filter if set
 	if cfg.Filter != nil {
 		var filtered []Repository
 		for _, r := range repositories {
-			if cfg.Filter.ShouldProcess(r.Name) {
+			if cfg.Filter.Match(r.Name) {
 				filtered = append(filtered, r)
 			} else {
-				common.Logger("debug", "Repository excluded by filter. repository=%s", r.Name)
+				reason := cfg.Filter.Explain(r.Name)
+				summary.Skipped = append(summary.Skipped, SkippedRepo{Repository: r.Name, Path: r.Path, Reason: reason})
+				common.Logger("debug", "Repository excluded by filter. repository=%s reason=%s", r.Name, reason)
 			}
 		}
 		repositories = filtered
 	}
+	metrics.ReposDiscovered.Add(int64(len(repositories)))
+
+	var results []RepoResult
+	if cfg.Parallel.Enabled && cfg.Parallel.MaxConcurrent > 1 {
+		results = updateRepositoriesParallel(ctx, repositories, cfg)
+	} else {
+		results = make([]RepoResult, 0, len(repositories))
+		for _, repo := range repositories {
+			results = append(results, runRepositoryUpdate(ctx, repo, cfg))
+		}
+	}
+	for _, result := range results {
+		summary.addResult(result)
+	}
+
+	summary.Total = len(repositories)
+	common.Logger("info", "Repository update completed. total=%d success=%d errors=%d stale_branches=%d", summary.Total, summary.Success, summary.Failed, summary.StaleBranches)
+
+	if summary.Failed > 0 {
+		return summary, &UpdateError{Summary: summary}
+	}
+	return summary, nil
+}
+
+// updateRepositoriesParallel updates the repositories in goroutines, at most cfg.Parallel.MaxConcurrent at a time.
+// It returns after all updates finish, with the results in the order of repositories.
+// A panic while updating a repository is recovered and reported as a failure of that repository.
+func updateRepositoriesParallel(ctx context.Context, repositories []Repository, cfg UpdateConfig) []RepoResult {
+	common.Logger("info", "Updating repositories in parallel. max_concurrent=%d", cfg.Parallel.MaxConcurrent)
+
+	// Each goroutine writes only its own index, so the slice needs no lock
+	results := make([]RepoResult, len(repositories))
+	semaphore := make(chan struct{}, cfg.Parallel.MaxConcurrent)
+	var wg sync.WaitGroup
+
+	for i, repo := range repositories {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, repo Repository) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			defer func() {
+				if r := recover(); r != nil {
+					common.Logger("error", "Panic while updating repository. repository=%s panic=%v", repo.Name, r)
+					metrics.ReposFailed.Inc()
+					results[i] = RepoResult{
+						Repository: repo.Name,
+						Path:       repo.Path,
+						Branch:     repo.CurrentBranch,
+						Status:     StatusFailed,
+						Error:      fmt.Sprintf("panic: %v", r),
+					}
+				}
+			}()
+
+			results[i] = runRepositoryUpdate(ctx, repo, cfg)
+		}(i, repo)
+	}
 
-	successCount := 0
-	errorCount := 0
+	wg.Wait()
+	return results
+}
 
-	for _, repo := range repositories {
-		fmt.Println("------------- BEGIN -------------")
-		common.Logger("info", "Updating repository. repository=%s path=%s branch=%s", repo.Name, repo.Path, repo.CurrentBranch)
+// runRepositoryUpdate updates the repository between the OnRepoStart and OnRepoComplete callbacks
+func runRepositoryUpdate(ctx context.Context, repo Repository, cfg UpdateConfig) RepoResult {
+	if cfg.OnRepoStart != nil {
+		cfg.OnRepoStart(repo)
+	}
+
+	result := updateRepository(ctx, repo, cfg)
+
+	if cfg.OnRepoComplete != nil {
+		cfg.OnRepoComplete(repo, result)
+	}
+	return result
+}
+
+// addResult counts the result of a repository in the summary
+func (s *UpdateSummary) addResult(result RepoResult) {
+	switch result.Status {
+	case StatusFailed:
+		s.Failed++
+	case StatusUpToDate:
+		s.Success++
+		s.UpToDate++
+	case StatusDryRun:
+		// Nothing was executed, so the repository is neither a success nor a failure
+	default:
+		s.Success++
+	}
+	s.StaleBranches += len(result.StaleBranches)
+	s.Results = append(s.Results, result)
+}
+
+// updateRepository runs the backup, pull and post-pull steps of one repository and returns its result
+func updateRepository(ctx context.Context, repo Repository, cfg UpdateConfig) RepoResult {
+	startTime := time.Now()
+	result := RepoResult{
+		Repository: repo.Name,
+		Path:       repo.Path,
+		Branch:     repo.CurrentBranch,
+	}
 
-		if branches, err := GetBranches(repo.Path); err == nil {
-			common.Logger("debug", "Local branches:\n%s", branches)
+	if cfg.DryRun {
+		common.Logger("info", "Dry run, repository would be updated. repository=%s path=%s branch=%s", repo.Name, repo.Path, repo.CurrentBranch)
+		result.Status = StatusDryRun
+		return result
+	}
+
+	if err := ctx.Err(); err != nil {
+		common.Logger("warning", "Update canceled before it started. repository=%s error=%v", repo.Name, err)
+		result.Status = StatusFailed
+		result.Error = err.Error()
+		metrics.ReposFailed.Inc()
+		return result
+	}
+
+	fmt.Println("------------- BEGIN -------------")
+	defer func() {
+		fmt.Println("---------------------------------")
+		fmt.Println()
+		fmt.Println()
+	}()
+	common.Logger("info", "Updating repository. repository=%s path=%s branch=%s", repo.Name, repo.Path, repo.CurrentBranch)
+
+	if branches, err := GetBranches(repo.Path); err == nil {
+		common.Logger("debug", "Local branches:\n%s", branches)
+	}
+
+	if cfg.PullOnlyIfBehind {
+		behind, err := GetCommitsBehind(repo.Path, cfg.FetchDepth)
+		if err != nil {
+			common.Logger("warning", "Could not check if repository is behind its upstream, pulling anyway. repository=%s error=%v", repo.Name, err)
+		} else if behind == 0 {
+			common.Logger("info", "Repository is already up to date, skipping pull. repository=%s", repo.Name)
+			result.Status = StatusUpToDate
+			result.Duration = time.Since(startTime)
+			return result
 		}
+	}
 
-		// Backup if enabled
-		if cfg.BackupEnabled && cfg.BackupManager != nil {
-			if _, err := cfg.BackupManager.CreateBackup(repo.Path, repo.Name); err != nil {
-				common.Logger("error", "Failed to create backup. repository=%s error=%v", repo.Name, err)
-			}
+	// Backup if enabled
+	if cfg.BackupEnabled && cfg.BackupManager != nil {
+		if _, err := cfg.BackupManager.CreateBackup(ctx, repo.Path, repo.Name); err != nil {
+			common.Logger("error", "Failed to create backup. repository=%s error=%v", repo.Name, err)
 		}
+	}
 
-		fmt.Printf("[INFO] Updating repository: '%s' on branch '%s'\n", repo.Name, repo.CurrentBranch)
-		fmt.Println("If necessary, enter login/password when prompted.")
+	fmt.Printf("[INFO] Updating repository: '%s' on branch '%s'\n", repo.Name, repo.CurrentBranch)
+	fmt.Println("If necessary, enter login/password when prompted.")
+	if HasHTTPSRemote(repo.Path) && !CredentialHelperConfigured(repo.Path) {
+		common.Logger("warning", "Repository has an HTTPS remote and no credential helper, git may ask for login/password. Use --git-credential-helper. repository=%s", repo.Name)
+	}
 
-		if err := PullRepository(repo.Path); err != nil {
-			common.Logger("error", "Failed to update repository. repository=%s error=%v", repo.Name, err)
-			errorCount++
+	if cfg.FetchAllRemotes {
+		fetchAllRemotes(repo, cfg.FetchDepth, cfg.FetchPrune)
+	}
+	if cfg.FetchBeforePull {
+		// The pull fetches the upstream again, so a failed fetch does not stop it
+		if err := FetchRepository(repo.Path, cfg.FetchPrune); err != nil {
+			common.Logger("warning", "Could not fetch the remotes before the pull. repository=%s error=%v", repo.Name, err)
 		} else {
-			successCount++
+			common.Logger("info", "Remotes fetched before the pull. repository=%s", repo.Name)
 		}
+	}
 
-		fmt.Println("---------------------------------")
-		fmt.Println()
-		fmt.Println()
+	headBefore, _ := GetHeadCommit(repo.Path)
+
+	stashed := false
+	if cfg.AutoStash && HasUncommittedChanges(repo.Path) {
+		if err := stashChanges(repo.Path, "updateGit auto-stash "+startTime.Format(time.RFC3339), cfg.Identity); err != nil {
+			common.Logger("error", "Failed to stash uncommitted changes. repository=%s error=%v", repo.Name, err)
+			result.Status = StatusFailed
+			result.Error = err.Error()
+			result.Duration = time.Since(startTime)
+			metrics.ReposFailed.Inc()
+			return result
+		}
+		stashed = true
+		common.Logger("info", "Uncommitted changes stashed before the pull. repository=%s", repo.Name)
 	}
 
-	common.Logger("info", "Repository update completed. total=%d success=%d errors=%d", len(repositories), successCount, errorCount)
+	// Only the pull is limited by the repository timeout, backups of big repositories may take longer
+	pullCtx := ctx
+	if cfg.Parallel.Timeout > 0 {
+		var cancel context.CancelFunc
+		pullCtx, cancel = context.WithTimeout(pullCtx, cfg.Parallel.Timeout)
+		defer cancel()
+	}
 
-	if errorCount > 0 {
-		common.Logger("fatal", "Update completed with %d errors out of %d repositories", errorCount, len(repositories))
+	factory := cfg.CommandFactory
+	if factory == nil {
+		factory = DefaultCommandFactory
 	}
-	return nil
+	output, err := pullRepository(pullCtx, factory, repo.Path, cfg.PullStrategy, cfg.Identity, cfg.Signing, cfg.ExtraPullArgs...)
+	if output != "" {
+		common.Logger("debug", "Git pull output. repository=%s\n%s", repo.Name, strings.TrimRight(output, "\n"))
+	}
+	if err != nil {
+		common.Logger("error", "Failed to update repository. repository=%s error=%v", repo.Name, err)
+		result.Status = StatusFailed
+		result.Error = err.Error()
+		if stashed {
+			restoreAutoStash(repo, &result)
+		}
+		result.Duration = time.Since(startTime)
+		metrics.ReposFailed.Inc()
+		return result
+	}
+
+	result.Status = StatusSuccess
+
+	if cfg.CommitMessageTemplate != "" {
+		applyCommitMessageTemplate(repo, cfg.CommitMessageTemplate, headBefore, cfg.Identity, cfg.Signing)
+	}
+
+	if cfg.Submodules.Enabled {
+		if err := UpdateSubmodules(repo.Path, cfg.Submodules); err != nil {
+			common.Logger("warning", "Could not update submodules. repository=%s error=%v", repo.Name, err)
+		}
+	}
+
+	if cfg.Tag.Name != "" {
+		tagRepository(repo, cfg.Tag, cfg.Identity)
+	}
+
+	if cfg.PostPullDiffStat {
+		result.DiffStat = postPullDiffStat(repo, headBefore)
+	}
+
+	if cfg.PruneWorktrees {
+		if err := PruneWorktrees(repo.Path); err != nil {
+			common.Logger("warning", "Could not prune worktrees. repository=%s error=%v", repo.Name, err)
+		}
+	}
+
+	if staleBranches, err := GetStaleTrackingBranches(repo.Path); err != nil {
+		common.Logger("warning", "Could not check stale tracking branches. repository=%s error=%v", repo.Name, err)
+	} else if len(staleBranches) > 0 {
+		common.Logger("info", "Branches deleted from the remote: %s. Remove them with 'updateGit prune'. repository=%s", strings.Join(staleBranches, ", "), repo.Name)
+		result.StaleBranches = staleBranches
+	}
+
+	// The changes are restored after the post-pull steps, which may amend the merge commit
+	if stashed {
+		restoreAutoStash(repo, &result)
+	}
+
+	// The outcome is recorded after the restore of the stash, which can still fail the update
+	if result.Status == StatusSuccess {
+		metrics.ReposUpdated.Inc()
+	} else {
+		metrics.ReposFailed.Inc()
+	}
+
+	result.Duration = time.Since(startTime)
+	return result
+}
+
+// restoreAutoStash pops the changes stashed by AutoStash. When the pop fails, the result is marked
+// as failed with the error.
+func restoreAutoStash(repo Repository, result *RepoResult) {
+	if err := popStashedChanges(repo.Path); err != nil {
+		common.Logger("error", "Failed to restore the stashed changes. repository=%s error=%v", repo.Name, err)
+		result.Status = StatusFailed
+		if result.Error != "" {
+			result.Error += "; "
+		}
+		result.Error += err.Error()
+		return
+	}
+	common.Logger("info", "Stashed changes restored after the pull. repository=%s", repo.Name)
 }
@@ -0,0 +1,925 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aeciopires/updateGit/internal/config"
+	"github.com/aeciopires/updateGit/internal/gittest"
+	"github.com/aeciopires/updateGit/internal/metrics"
+)
+
+func TestIsGitRepository(t *testing.T) {
+	tempDir := t.TempDir()
+
+	withGitDir := filepath.Join(tempDir, "with-git-dir")
+	if err := os.MkdirAll(filepath.Join(withGitDir, ".git"), config.PermissionDir); err != nil {
+		t.Fatalf("could not create directory: %v", err)
+	}
+
+	withoutGit := filepath.Join(tempDir, "without-git")
+	if err := os.MkdirAll(withoutGit, config.PermissionDir); err != nil {
+		t.Fatalf("could not create directory: %v", err)
+	}
+
+	worktree := filepath.Join(tempDir, "worktree")
+	if err := os.MkdirAll(worktree, config.PermissionDir); err != nil {
+		t.Fatalf("could not create directory: %v", err)
+	}
+	gitFile := "gitdir: " + filepath.Join(withGitDir, ".git", "worktrees", "worktree") + "\n"
+	if err := os.WriteFile(filepath.Join(worktree, ".git"), []byte(gitFile), config.PermissionFile); err != nil {
+		t.Fatalf("could not write .git file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected bool
+	}{
+		{name: "directory with .git subdirectory", path: withGitDir, expected: true},
+		{name: "directory without .git", path: withoutGit, expected: false},
+		{name: "directory with .git file", path: worktree, expected: true},
+		{name: "non-existent path", path: filepath.Join(tempDir, "missing"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := IsGitRepository(tt.path); result != tt.expected {
+				t.Errorf("IsGitRepository(%s) = %t, expected %t", tt.path, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetCurrentBranch(t *testing.T) {
+	repoDir := t.TempDir()
+	gittest.InitRepository(t, repoDir)
+
+	t.Run("normal branch", func(t *testing.T) {
+		branch, err := GetCurrentBranch(repoDir)
+		if err != nil || branch != "main" {
+			t.Errorf("expected (main, nil), got (%s, %v)", branch, err)
+		}
+	})
+
+	t.Run("branch with slash", func(t *testing.T) {
+		gittest.Run(t, repoDir, "checkout", "-b", "feature/my-feature")
+
+		branch, err := GetCurrentBranch(repoDir)
+		if err != nil || branch != "feature/my-feature" {
+			t.Errorf("expected (feature/my-feature, nil), got (%s, %v)", branch, err)
+		}
+	})
+
+	t.Run("detached HEAD", func(t *testing.T) {
+		gittest.Run(t, repoDir, "checkout", "HEAD~0")
+
+		branch, err := GetCurrentBranch(repoDir)
+		var gitErr *GitError
+		if !errors.As(err, &gitErr) {
+			t.Fatalf("expected a *GitError for detached HEAD, got %v", err)
+		}
+		if gitErr.Operation != "symbolic-ref" || branch != "unknown" {
+			t.Errorf("unexpected result for detached HEAD: branch=%s error=%v", branch, err)
+		}
+	})
+}
+
+func TestUpdateRepositoriesParallelRace(t *testing.T) {
+	workDir, err := os.MkdirTemp("", "updateGit-race-")
+	if err != nil {
+		t.Fatalf("could not create temp directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(workDir) })
+
+	baseDir := filepath.Join(workDir, "repos")
+	if err := os.MkdirAll(baseDir, config.PermissionDir); err != nil {
+		t.Fatalf("could not create directory: %v", err)
+	}
+
+	// One remote cloned several times, so every clone has something to pull
+	bareRepo, seedRepo := gittest.NewRemote(t)
+
+	const repoCount = 8
+	for i := 0; i < repoCount; i++ {
+		gittest.Clone(t, bareRepo, baseDir, fmt.Sprintf("repo-%d", i))
+	}
+
+	gittest.CommitFile(t, seedRepo, "CHANGELOG.md", "new", "second commit")
+	gittest.Run(t, seedRepo, "push", "origin", "HEAD:main")
+
+	summary, err := UpdateRepositoriesWithSummary(UpdateConfig{
+		BaseDir: baseDir,
+		Parallel: ParallelUpdateConfig{
+			Enabled:       true,
+			MaxConcurrent: 4,
+			Timeout:       time.Minute,
+		},
+	})
+	if err != nil {
+		t.Fatalf("UpdateRepositoriesWithSummary returned error: %v", err)
+	}
+
+	if summary.Total != repoCount || summary.Success != repoCount || len(summary.Results) != repoCount {
+		t.Errorf("expected %d successful repositories, got %+v", repoCount, summary)
+	}
+}
+
+func TestUpdateRepositoriesCallbacks(t *testing.T) {
+	baseDir := t.TempDir()
+
+	bareRepo, _ := gittest.NewRemote(t)
+	for _, name := range []string{"api", "web"} {
+		gittest.Clone(t, bareRepo, baseDir, name)
+	}
+
+	var mu sync.Mutex
+	started := map[string]bool{}
+	completed := map[string]string{}
+	cfg := NewUpdateConfig(
+		WithBaseDir(baseDir),
+		WithParallel(true, 2),
+		WithOnRepoStart(func(repo Repository) {
+			mu.Lock()
+			defer mu.Unlock()
+			started[repo.Name] = true
+		}),
+		WithOnRepoComplete(func(repo Repository, result RepoResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			if !started[repo.Name] {
+				t.Errorf("OnRepoComplete called before OnRepoStart for %s", repo.Name)
+			}
+			completed[repo.Name] = result.Status
+		}),
+	)
+
+	if _, err := UpdateRepositoriesWithSummary(cfg); err != nil {
+		t.Fatalf("UpdateRepositoriesWithSummary returned error: %v", err)
+	}
+
+	for _, name := range []string{"api", "web"} {
+		if completed[name] != StatusSuccess {
+			t.Errorf("expected OnRepoComplete with status '%s' for %s, got %v", StatusSuccess, name, completed)
+		}
+	}
+}
+
+func TestUpdateRepositoriesCommandFactory(t *testing.T) {
+	baseDir := t.TempDir()
+	repoDir := filepath.Join(baseDir, "project")
+	if err := os.MkdirAll(repoDir, config.PermissionDir); err != nil {
+		t.Fatalf("could not create directory: %v", err)
+	}
+	gittest.InitRepository(t, repoDir)
+
+	// The repository has no remote, so only the fake pull can succeed
+	var pulls []string
+	factory := func(dir, name string, args ...string) *exec.Cmd {
+		if name == "git" && len(args) > 0 && args[0] == "pull" {
+			pulls = append(pulls, dir)
+			return exec.Command("echo", "Already up to date.")
+		}
+		return DefaultCommandFactory(dir, name, args...)
+	}
+
+	summary, err := UpdateRepositoriesWithSummary(NewUpdateConfig(WithBaseDir(baseDir), WithCommandFactory(factory)))
+	if err != nil {
+		t.Fatalf("UpdateRepositoriesWithSummary returned error: %v", err)
+	}
+
+	if len(pulls) != 1 || pulls[0] != repoDir {
+		t.Errorf("expected the fake pull in %s, got %v", repoDir, pulls)
+	}
+	if summary.Success != 1 {
+		t.Errorf("expected 1 successful repository, got %+v", summary)
+	}
+}
+
+func TestUpdateRepositoriesReturnsUpdateError(t *testing.T) {
+	baseDir := t.TempDir()
+
+	// The remote is removed after the clone, so the pull fails
+	bareRepo, _ := gittest.NewRemote(t)
+	gittest.Clone(t, bareRepo, baseDir, "broken")
+	if err := os.RemoveAll(bareRepo); err != nil {
+		t.Fatalf("could not remove remote: %v", err)
+	}
+
+	summary, err := UpdateRepositoriesWithSummary(UpdateConfig{BaseDir: baseDir})
+
+	var updateErr *UpdateError
+	if !errors.As(err, &updateErr) {
+		t.Fatalf("expected *UpdateError, got %v", err)
+	}
+	if updateErr.Summary != summary || summary.Failed != 1 || summary.Total != 1 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+}
+
+func TestFindRepositoriesRecursive(t *testing.T) {
+	baseDir := t.TempDir()
+	for _, path := range []string{"top", "work/api", "oss/lib", "a/b/c/deep", "top/vendor/nested"} {
+		dir := filepath.Join(baseDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(dir, config.PermissionDir); err != nil {
+			t.Fatalf("could not create directory: %v", err)
+		}
+		gittest.InitRepository(t, dir)
+	}
+
+	tests := []struct {
+		maxDepth int
+		expected []string
+	}{
+		{maxDepth: 1, expected: []string{"top"}},
+		{maxDepth: 2, expected: []string{"oss/lib", "top", "work/api"}},
+		{maxDepth: 0, expected: []string{"a/b/c/deep", "oss/lib", "top", "work/api"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("max depth %d", tt.maxDepth), func(t *testing.T) {
+			repositories, err := FindRepositoriesRecursive(baseDir, tt.maxDepth, false)
+			if err != nil {
+				t.Fatalf("FindRepositoriesRecursive returned error: %v", err)
+			}
+
+			var names []string
+			for _, repo := range repositories {
+				names = append(names, repo.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected repositories %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestFindRepositoriesRecursiveSubmodules(t *testing.T) {
+	// The base directory is a repository with the submodule "lib"
+	baseDir := t.TempDir()
+	gittest.InitRepository(t, baseDir)
+	gitModules := "[submodule \"lib\"]\n\tpath = lib\n\turl = https://example.com/lib.git\n"
+	if err := os.WriteFile(filepath.Join(baseDir, ".gitmodules"), []byte(gitModules), config.PermissionFile); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+	for _, name := range []string{"lib", "tools"} {
+		dir := filepath.Join(baseDir, name)
+		if err := os.MkdirAll(dir, config.PermissionDir); err != nil {
+			t.Fatalf("could not create directory: %v", err)
+		}
+		gittest.InitRepository(t, dir)
+	}
+
+	tests := []struct {
+		include  bool
+		expected []string
+	}{
+		{include: false, expected: []string{"tools"}},
+		{include: true, expected: []string{"lib", "tools"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("include %t", tt.include), func(t *testing.T) {
+			repositories, err := FindRepositoriesRecursive(baseDir, 1, tt.include)
+			if err != nil {
+				t.Fatalf("FindRepositoriesRecursive returned error: %v", err)
+			}
+
+			var names []string
+			for _, repo := range repositories {
+				names = append(names, repo.Name)
+			}
+			if !slices.Equal(names, tt.expected) {
+				t.Errorf("expected repositories %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestGetTagsAtHead(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(t *testing.T, repoDir string)
+		expected []string
+	}{
+		{name: "no tags", setup: func(t *testing.T, repoDir string) {}, expected: []string{}},
+		{
+			name: "lightweight and annotated tags",
+			setup: func(t *testing.T, repoDir string) {
+				gittest.Run(t, repoDir, "tag", "v1.0.0")
+				gittest.Run(t, repoDir, "tag", "-a", "stable", "-m", "Stable")
+			},
+			expected: []string{"stable", "v1.0.0"},
+		},
+		{
+			name: "tags of older commits are not listed",
+			setup: func(t *testing.T, repoDir string) {
+				gittest.Run(t, repoDir, "tag", "v0.9.0")
+				gittest.CommitFile(t, repoDir, "CHANGELOG.md", "v1.0.0", "release")
+				gittest.Run(t, repoDir, "tag", "v1.0.0")
+			},
+			expected: []string{"v1.0.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoDir := t.TempDir()
+			gittest.InitRepository(t, repoDir)
+			tt.setup(t, repoDir)
+
+			tags, err := GetTagsAtHead(repoDir)
+			if err != nil {
+				t.Fatalf("GetTagsAtHead returned error: %v", err)
+			}
+			if !slices.Equal(tags, tt.expected) {
+				t.Errorf("expected tags %v, got %v", tt.expected, tags)
+			}
+		})
+	}
+
+	if _, err := GetTagsAtHead(t.TempDir()); err == nil {
+		t.Error("expected an error outside a git repository")
+	}
+}
+
+func TestParseStaleTrackingBranches(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected []string
+	}{
+		{name: "no branches", output: "", expected: nil},
+		{name: "upstream exists", output: "* main 1a2b3c4 [origin/main] Initial commit\n", expected: nil},
+		{name: "upstream ahead", output: "  dev  1a2b3c4 [origin/dev: ahead 2] Work in progress\n", expected: nil},
+		{name: "no upstream", output: "  local 1a2b3c4 Local only\n", expected: nil},
+		{name: "current branch gone", output: "* x 1a2b3c4 [origin/x: gone] Commit message\n", expected: []string{"x"}},
+		{
+			name: "several branches",
+			output: "  feature/login 1a2b3c4 [origin/feature/login: gone] Login\n" +
+				"* main          5d6e7f8 [origin/main] Release\n" +
+				"+ hotfix        9a8b7c6 [upstream/hotfix: gone] Fix checked out in a worktree\n",
+			expected: []string{"feature/login", "hotfix"},
+		},
+		{name: "gone in the message", output: "  docs 1a2b3c4 [origin/docs] Mark [origin/old: gone]\n", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseStaleTrackingBranches(tt.output); !slices.Equal(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoadIgnoreFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected map[string]bool
+	}{
+		{name: "empty file", content: "", expected: map[string]bool{}},
+		{name: "one name per line", content: "api\nweb\n", expected: map[string]bool{"api": true, "web": true}},
+		{name: "comments", content: "# archived projects\napi\n  # indented comment\n", expected: map[string]bool{"api": true}},
+		{name: "blank lines", content: "\n\napi\n   \n\tweb\n\n", expected: map[string]bool{"api": true, "web": true}},
+		{name: "trailing slash and spaces", content: "  work/api/  \n", expected: map[string]bool{"work/api": true}},
+		{name: "windows line endings", content: "# legacy\r\napi\r\n", expected: map[string]bool{"api": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), IgnoreFileName)
+			if err := os.WriteFile(path, []byte(tt.content), config.PermissionFile); err != nil {
+				t.Fatalf("could not write file: %v", err)
+			}
+
+			ignored, err := loadIgnoreFile(path)
+			if err != nil {
+				t.Fatalf("loadIgnoreFile returned error: %v", err)
+			}
+			if !reflect.DeepEqual(ignored, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, ignored)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		ignored, err := loadIgnoreFile(filepath.Join(t.TempDir(), IgnoreFileName))
+		if err != nil || len(ignored) != 0 {
+			t.Errorf("expected no entries and no error, got %v, %v", ignored, err)
+		}
+	})
+
+	t.Run("unreadable file", func(t *testing.T) {
+		// A directory can not be read as a file
+		if _, err := loadIgnoreFile(t.TempDir()); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
+
+func TestFetchRemotePrune(t *testing.T) {
+	workDir := t.TempDir()
+	bareRepo, seedRepo := gittest.NewRemote(t)
+	gittest.Run(t, seedRepo, "push", "origin", "HEAD:feature")
+	cloneRepo := gittest.Clone(t, bareRepo, workDir, "clone")
+	gittest.Run(t, seedRepo, "push", "origin", "--delete", "feature")
+
+	if err := FetchRemote(cloneRepo, "origin", 0, false); err != nil {
+		t.Fatalf("FetchRemote returned error: %v", err)
+	}
+	if branches := gittest.Run(t, cloneRepo, "branch", "-r"); !strings.Contains(branches, "origin/feature") {
+		t.Fatalf("expected origin/feature to be kept without prune, got:\n%s", branches)
+	}
+
+	if err := FetchRemote(cloneRepo, "origin", 0, true); err != nil {
+		t.Fatalf("FetchRemote returned error: %v", err)
+	}
+	if branches := gittest.Run(t, cloneRepo, "branch", "-r"); strings.Contains(branches, "origin/feature") {
+		t.Errorf("expected origin/feature to be pruned, got:\n%s", branches)
+	}
+}
+
+func TestUpdateRepositoriesFetchBeforePull(t *testing.T) {
+	baseDir := t.TempDir()
+	bareRepo, seedRepo := gittest.NewRemote(t)
+	cloneRepo := gittest.Clone(t, bareRepo, baseDir, "project")
+	gittest.Run(t, cloneRepo, "remote", "add", "upstream", bareRepo)
+	gittest.CommitFile(t, seedRepo, "README.md", "second", "second commit")
+	gittest.Run(t, seedRepo, "push", "origin", "HEAD:main")
+	remoteHead := strings.TrimSpace(gittest.Run(t, seedRepo, "rev-parse", "HEAD"))
+
+	// The fake pull records the remote-tracking branches it finds, without fetching them
+	var trackingHeads []string
+	factory := func(dir, name string, args ...string) *exec.Cmd {
+		if name == "git" && len(args) > 0 && args[0] == "pull" {
+			for _, ref := range []string{"origin/main", "upstream/main"} {
+				trackingHeads = append(trackingHeads, strings.TrimSpace(gittest.Run(t, dir, "rev-parse", ref)))
+			}
+			return exec.Command("echo", "Already up to date.")
+		}
+		return DefaultCommandFactory(dir, name, args...)
+	}
+
+	summary, err := UpdateRepositoriesWithSummary(NewUpdateConfig(
+		WithBaseDir(baseDir),
+		WithCommandFactory(factory),
+		WithFetchBeforePull(true),
+	))
+	if err != nil {
+		t.Fatalf("UpdateRepositoriesWithSummary returned error: %v", err)
+	}
+	if summary.Success != 1 {
+		t.Fatalf("expected 1 successful repository, got %+v", summary)
+	}
+
+	// Both remotes were fetched before the pull started
+	if len(trackingHeads) != 2 || trackingHeads[0] != remoteHead || trackingHeads[1] != remoteHead {
+		t.Errorf("expected origin/main and upstream/main at %s when the pull started, got %v", remoteHead, trackingHeads)
+	}
+}
+
+func TestPullRepositoryStrategy(t *testing.T) {
+	// The rebase rewrites the local commit, so git needs a committer identity
+	t.Setenv("GIT_COMMITTER_NAME", "updateGit test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@updategit.local")
+
+	tests := []struct {
+		strategy PullStrategy
+		wantErr  bool
+	}{
+		{strategy: PullStrategyFFOnly, wantErr: true},
+		{strategy: PullStrategyRebase, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			workDir := t.TempDir()
+			bareRepo, seedRepo := gittest.NewRemote(t)
+			cloneRepo := gittest.Clone(t, bareRepo, workDir, "clone")
+
+			// Local and remote commits make the branches diverge
+			gittest.Run(t, seedRepo, "commit", "--allow-empty", "-m", "remote commit")
+			gittest.Run(t, seedRepo, "push", "origin", "HEAD:main")
+			gittest.Run(t, cloneRepo, "commit", "--allow-empty", "-m", "local commit")
+
+			_, err := PullRepository(context.Background(), cloneRepo, tt.strategy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PullRepository with strategy %s returned error %v, wantErr %t", tt.strategy, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if merges := gittest.Run(t, cloneRepo, "rev-list", "--merges", "HEAD"); merges != "" {
+				t.Errorf("expected a linear history after the rebase, got merge commits:\n%s", merges)
+			}
+			if log := gittest.Run(t, cloneRepo, "log", "--format=%s", "-2"); log != "local commit\nremote commit\n" {
+				t.Errorf("expected the local commit on top of the remote commit, got:\n%s", log)
+			}
+		})
+	}
+}
+
+func TestUpdateRepositoriesAutoStash(t *testing.T) {
+	// The stash entry is a commit, so git needs an identity
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "updateGit test")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "test@updategit.local")
+	}
+
+	tests := []struct {
+		name          string
+		remoteFile    string
+		expectStatus  string
+		expectUpdated int64
+		expectFailed  int64
+	}{
+		{name: "changes restored", remoteFile: "CHANGELOG.md", expectStatus: StatusSuccess, expectUpdated: 1},
+		{name: "changes conflict with the pull", remoteFile: "README.md", expectStatus: StatusFailed, expectFailed: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseDir := t.TempDir()
+			bareRepo, seedRepo := gittest.NewRemote(t)
+			cloneRepo := gittest.Clone(t, bareRepo, baseDir, "project")
+
+			gittest.CommitFile(t, seedRepo, tt.remoteFile, "remote", "remote change")
+			gittest.Run(t, seedRepo, "push", "origin", "HEAD:main")
+
+			if err := os.WriteFile(filepath.Join(cloneRepo, "README.md"), []byte("local"), config.PermissionFile); err != nil {
+				t.Fatalf("could not write file: %v", err)
+			}
+
+			updatedBefore, failedBefore := metrics.ReposUpdated.Value(), metrics.ReposFailed.Value()
+			summary, _ := UpdateRepositoriesWithSummary(NewUpdateConfig(WithBaseDir(baseDir), WithAutoStash(true)))
+			if len(summary.Results) != 1 || summary.Results[0].Status != tt.expectStatus {
+				t.Fatalf("expected status '%s', got %+v", tt.expectStatus, summary.Results)
+			}
+			// The repository is counted once, even when the pull succeeded before the restore failed
+			updated, failed := metrics.ReposUpdated.Value()-updatedBefore, metrics.ReposFailed.Value()-failedBefore
+			if updated != tt.expectUpdated || failed != tt.expectFailed {
+				t.Errorf("expected %d updated and %d failed in the metrics, got %d and %d", tt.expectUpdated, tt.expectFailed, updated, failed)
+			}
+
+			if log := gittest.Run(t, cloneRepo, "log", "--format=%s", "-1"); log != "remote change\n" {
+				t.Errorf("expected the remote commit to be pulled, got %q", log)
+			}
+
+			data, _ := os.ReadFile(filepath.Join(cloneRepo, "README.md"))
+			stashList := gittest.Run(t, cloneRepo, "stash", "list")
+			if tt.expectStatus == StatusSuccess {
+				if string(data) != "local" || stashList != "" {
+					t.Errorf("expected the local change restored and no stash entry, got README.md %q and stash %q", data, stashList)
+				}
+				return
+			}
+			if HasUncommittedChanges(cloneRepo) || !strings.Contains(stashList, "updateGit auto-stash") {
+				t.Errorf("expected a clean working tree and the changes kept in the stash, got stash %q", stashList)
+			}
+		})
+	}
+}
+
+func TestGetRepoTopLevel(t *testing.T) {
+	repoDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("could not resolve temp directory: %v", err)
+	}
+	gittest.Run(t, repoDir, "init", "-b", "main")
+
+	subDir := filepath.Join(repoDir, "internal", "pkg")
+	if err := os.MkdirAll(subDir, config.PermissionDir); err != nil {
+		t.Fatalf("could not create directory: %v", err)
+	}
+
+	topLevel, err := GetRepoTopLevel(subDir)
+	if err != nil {
+		t.Fatalf("GetRepoTopLevel returned error: %v", err)
+	}
+	if topLevel != repoDir {
+		t.Errorf("GetRepoTopLevel(%s) = %s, want %s", subDir, topLevel, repoDir)
+	}
+
+	if _, err := GetRepoTopLevel(t.TempDir()); err == nil {
+		t.Error("expected an error outside a repository")
+	}
+}
+
+func TestPullRepositoryTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake git command is a shell script")
+	}
+
+	// A fake git that hangs like a pull waiting for an unresponsive remote
+	binDir := t.TempDir()
+	fakeGit := "#!/bin/sh\nexec sleep 30\n"
+	if err := os.WriteFile(filepath.Join(binDir, "git"), []byte(fakeGit), config.PermissionBinary); err != nil {
+		t.Fatalf("could not write fake git: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := PullRepository(ctx, t.TempDir(), "")
+	elapsed := time.Since(start)
+
+	var gitErr *GitError
+	if !errors.As(err, &gitErr) {
+		t.Fatalf("expected *GitError, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error wrapping context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf("pull was not killed at the deadline, it took %v", elapsed)
+	}
+}
+
+func TestSubmoduleOptionsArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     SubmoduleOptions
+		expected string
+	}{
+		{name: "git defaults", opts: SubmoduleOptions{Enabled: true}, expected: "submodule update --init --recursive"},
+		{name: "jobs", opts: SubmoduleOptions{Enabled: true, Jobs: 4}, expected: "submodule update --init --recursive --jobs=4"},
+		{name: "jobs and depth", opts: SubmoduleOptions{Enabled: true, Jobs: 2, Depth: 1}, expected: "submodule update --init --recursive --jobs=2 --depth=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(tt.opts.args(), " "); got != tt.expected {
+				t.Errorf("expected '%s', got '%s'", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestUpdateRepositoriesCanceledContext(t *testing.T) {
+	baseDir := t.TempDir()
+	for _, name := range []string{"api", "web"} {
+		repoDir := filepath.Join(baseDir, name)
+		if err := os.MkdirAll(repoDir, config.PermissionDir); err != nil {
+			t.Fatalf("could not create directory: %v", err)
+		}
+		gittest.InitRepository(t, repoDir)
+	}
+
+	var pulls int
+	factory := func(dir, name string, args ...string) *exec.Cmd {
+		pulls++
+		return DefaultCommandFactory(dir, name, args...)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	summary, err := UpdateRepositoriesWithSummaryContext(ctx, NewUpdateConfig(WithBaseDir(baseDir), WithCommandFactory(factory)))
+
+	var updateErr *UpdateError
+	if !errors.As(err, &updateErr) {
+		t.Fatalf("expected *UpdateError, got %v", err)
+	}
+	if summary.Failed != 2 || pulls != 0 {
+		t.Errorf("expected both repositories to fail without a pull, got failed=%d pulls=%d", summary.Failed, pulls)
+	}
+	for _, result := range summary.Results {
+		if result.Error != context.Canceled.Error() {
+			t.Errorf("expected the cancellation error, got %q", result.Error)
+		}
+	}
+}
+
+func TestCredentialHelperConfigured(t *testing.T) {
+	// Only the configuration of the repository and of updateGit is considered
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Cleanup(func() { SetConfigOptions(ConfigOptions{}) })
+
+	repoDir := t.TempDir()
+	gittest.InitRepository(t, repoDir)
+	gittest.Run(t, repoDir, "remote", "add", "mirror", "git@example.com:team/project.git")
+	if HasHTTPSRemote(repoDir) {
+		t.Error("expected no HTTPS remote")
+	}
+
+	gittest.Run(t, repoDir, "remote", "add", "origin", "HTTPS://example.com/team/project.git")
+	if !HasHTTPSRemote(repoDir) {
+		t.Error("expected an HTTPS remote")
+	}
+
+	if CredentialHelperConfigured(repoDir) {
+		t.Error("expected no credential helper")
+	}
+	SetConfigOptions(ConfigOptions{CredentialHelper: "cache"})
+	if !CredentialHelperConfigured(repoDir) {
+		t.Error("expected the credential helper of --git-credential-helper")
+	}
+}
+
+func TestSafeDirectoryConfig(t *testing.T) {
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Cleanup(func() { SetConfigOptions(ConfigOptions{}) })
+
+	repoDir := t.TempDir()
+	gittest.InitRepository(t, repoDir)
+
+	SetConfigOptions(ConfigOptions{SafeDirectory: "*"})
+	output, err := newGitCommand(repoDir, "config", "--get-all", "safe.directory").Output()
+	if err != nil {
+		t.Fatalf("git config failed: %v", err)
+	}
+	if got := strings.TrimSpace(string(output)); got != "*" {
+		t.Errorf("expected safe.directory '*', got '%s'", got)
+	}
+}
+
+func TestConfigEnvKeys(t *testing.T) {
+	if keys := ConfigEnvKeys(ConfigOptions{}, CommitIdentity{}, SigningOptions{}); len(keys) != 0 {
+		t.Errorf("expected no keys without git settings, got %v", keys)
+	}
+	// git is not run, so an old version can not fail the check
+	if err := CheckConfigEnvSupport(ConfigOptions{}, CommitIdentity{}, SigningOptions{}); err != nil {
+		t.Errorf("expected no error without git settings, got %v", err)
+	}
+
+	keys := ConfigEnvKeys(ConfigOptions{SafeDirectory: "*"}, CommitIdentity{Name: "Bot"}, SigningOptions{Enabled: true})
+	if !slices.Equal(keys, []string{"safe.directory", "user.name", "commit.gpgSign"}) {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+}
+
+func TestUpdateRepositoriesGitConfig(t *testing.T) {
+	baseDir := t.TempDir()
+	repoDir := filepath.Join(baseDir, "project")
+	if err := os.MkdirAll(repoDir, config.PermissionDir); err != nil {
+		t.Fatalf("could not create directory: %v", err)
+	}
+	gittest.InitRepository(t, repoDir)
+
+	SetConfigOptions(ConfigOptions{MaxPackSize: 64})
+	t.Cleanup(func() { SetConfigOptions(ConfigOptions{}) })
+
+	// The fake pull records the options of its git commands
+	var pullOptions []ConfigOptions
+	factory := func(dir, name string, args ...string) *exec.Cmd {
+		if name == "git" && len(args) > 0 && args[0] == "pull" {
+			pullOptions = append(pullOptions, currentConfigOptions())
+			return exec.Command("echo", "Already up to date.")
+		}
+		return DefaultCommandFactory(dir, name, args...)
+	}
+
+	gitConfig := ConfigOptions{SafeDirectory: "*", CredentialHelper: "cache"}
+	if _, err := UpdateRepositoriesWithSummary(NewUpdateConfig(
+		WithBaseDir(baseDir),
+		WithCommandFactory(factory),
+		WithGitConfig(gitConfig),
+	)); err != nil {
+		t.Fatalf("UpdateRepositoriesWithSummary returned error: %v", err)
+	}
+
+	if len(pullOptions) != 1 || pullOptions[0] != gitConfig {
+		t.Errorf("expected the options of WithGitConfig in the pull, got %+v", pullOptions)
+	}
+	if got := currentConfigOptions(); got != (ConfigOptions{MaxPackSize: 64}) {
+		t.Errorf("expected the options of SetConfigOptions after the update, got %+v", got)
+	}
+}
+
+func TestUpdateRepositoriesSlowPullRepoTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake pull uses sleep")
+	}
+
+	baseDir := t.TempDir()
+	repoDir := filepath.Join(baseDir, "project")
+	if err := os.MkdirAll(repoDir, config.PermissionDir); err != nil {
+		t.Fatalf("could not create directory: %v", err)
+	}
+	gittest.InitRepository(t, repoDir)
+
+	// The fake pull takes longer than the short timeout, but much less than DefaultRepoTimeoutSec
+	factory := func(dir, name string, args ...string) *exec.Cmd {
+		if name == "git" && len(args) > 0 && args[0] == "pull" {
+			cmd := exec.Command("sleep", "1")
+			cmd.Dir = dir
+			return cmd
+		}
+		return DefaultCommandFactory(dir, name, args...)
+	}
+
+	tests := []struct {
+		name    string
+		opts    []UpdateOption
+		success int
+	}{
+		{name: "default timeout", opts: nil, success: 1},
+		{name: "zero keeps the default", opts: []UpdateOption{WithRepoTimeout(0)}, success: 1},
+		{name: "timeout shorter than the pull", opts: []UpdateOption{WithRepoTimeout(200 * time.Millisecond)}, success: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]UpdateOption{WithBaseDir(baseDir), WithCommandFactory(factory)}, tt.opts...)
+			summary, _ := UpdateRepositoriesWithSummary(NewUpdateConfig(opts...))
+			if summary.Success != tt.success || summary.Total != 1 {
+				t.Errorf("expected %d successful repositories, got %+v", tt.success, summary)
+			}
+		})
+	}
+}
+
+func TestUpdateRepositoriesTotalTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake pull uses sleep")
+	}
+
+	baseDir := t.TempDir()
+	for _, name := range []string{"api", "web"} {
+		repoDir := filepath.Join(baseDir, name)
+		if err := os.MkdirAll(repoDir, config.PermissionDir); err != nil {
+			t.Fatalf("could not create directory: %v", err)
+		}
+		gittest.InitRepository(t, repoDir)
+	}
+
+	factory := func(dir, name string, args ...string) *exec.Cmd {
+		if name == "git" && len(args) > 0 && args[0] == "pull" {
+			cmd := exec.Command("sleep", "5")
+			cmd.Dir = dir
+			return cmd
+		}
+		return DefaultCommandFactory(dir, name, args...)
+	}
+
+	start := time.Now()
+	summary, err := UpdateRepositoriesWithSummary(NewUpdateConfig(
+		WithBaseDir(baseDir),
+		WithCommandFactory(factory),
+		WithTotalTimeout(300*time.Millisecond),
+	))
+	elapsed := time.Since(start)
+
+	var updateErr *UpdateError
+	if !errors.As(err, &updateErr) {
+		t.Fatalf("expected *UpdateError, got %v", err)
+	}
+	// The first pull is killed at the deadline and the second one is not started
+	if summary.Failed != 2 {
+		t.Errorf("expected both repositories to fail, got %+v", summary)
+	}
+	if elapsed > 4*time.Second {
+		t.Errorf("update was not stopped at the total deadline, it took %v", elapsed)
+	}
+}
+
+func TestUpdateRepositoriesCommitIdentity(t *testing.T) {
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	baseDir := t.TempDir()
+
+	bareRepo, _ := gittest.NewRemote(t)
+	gittest.Clone(t, bareRepo, baseDir, "project")
+
+	// The identity of the properties is not used by the update
+	saved := config.Properties.Git
+	t.Cleanup(func() { config.Properties.Git = saved })
+	config.Properties.Git.UserName = "Properties User"
+
+	summary, err := UpdateRepositoriesWithSummary(NewUpdateConfig(
+		WithBaseDir(baseDir),
+		WithTag(TagOptions{Name: "synced"}),
+		WithCommitIdentity("Release Bot", "bot@example.com"),
+	))
+	if err != nil {
+		t.Fatalf("UpdateRepositoriesWithSummary returned error: %v", err)
+	}
+	if summary.Success != 1 {
+		t.Fatalf("expected a successful repository, got %+v", summary)
+	}
+
+	tagger := gittest.Run(t, filepath.Join(baseDir, "project"), "for-each-ref", "--format=%(taggername) %(taggeremail)", "refs/tags/synced")
+	if got := strings.TrimSpace(tagger); got != "Release Bot <bot@example.com>" {
+		t.Errorf("expected the tagger of WithCommitIdentity, got %q", got)
+	}
+}
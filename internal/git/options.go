@@ -0,0 +1,234 @@
+package git
+
+import "time"
+
+// UpdateOption changes a setting of the UpdateConfig created by NewUpdateConfig
+type UpdateOption func(*UpdateConfig)
+
+// NewUpdateConfig creates an UpdateConfig with the default timeouts and applies the options in order.
+//
+// Example:
+//
+//	cfg := git.NewUpdateConfig(
+//		git.WithBaseDir("/home/user/git"),
+//		git.WithParallel(true, 5),
+//		git.WithRepoTimeout(2*time.Minute),
+//	)
+func NewUpdateConfig(opts ...UpdateOption) UpdateConfig {
+	cfg := UpdateConfig{
+		Parallel: ParallelUpdateConfig{
+			Timeout: DefaultRepoTimeoutSec * time.Second,
+		},
+		TotalTimeout:   DefaultTotalTimeoutSec * time.Second,
+		CommandFactory: DefaultCommandFactory,
+		MaxDepth:       1,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return cfg
+}
+
+// WithBaseDir sets the directory where the repositories are searched
+func WithBaseDir(baseDir string) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.BaseDir = baseDir
+	}
+}
+
+// WithMaxDepth sets the levels of directories scanned for repositories below the base directory. 0 means unlimited
+func WithMaxDepth(maxDepth int) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.MaxDepth = maxDepth
+	}
+}
+
+// WithIncludeSubmoduleRepos updates the repositories that are submodules of the base directory repository
+func WithIncludeSubmoduleRepos(enabled bool) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.IncludeSubmoduleRepos = enabled
+	}
+}
+
+// WithParallel enables or disables parallel updates with up to maxConcurrent repositories at a time
+func WithParallel(enabled bool, maxConcurrent int) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.Parallel.Enabled = enabled
+		cfg.Parallel.MaxConcurrent = maxConcurrent
+	}
+}
+
+// WithRepoTimeout sets the timeout of the update of each repository.
+// A value lower or equal to 0 keeps the default (DefaultRepoTimeoutSec).
+func WithRepoTimeout(d time.Duration) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		if d > 0 {
+			cfg.Parallel.Timeout = d
+		}
+	}
+}
+
+// WithTotalTimeout sets the timeout of the update of all repositories. 0 means no limit.
+func WithTotalTimeout(d time.Duration) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.TotalTimeout = d
+	}
+}
+
+// WithGitConfig sets the git configuration keys passed to the git commands of the update
+func WithGitConfig(options ConfigOptions) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.GitConfig = &options
+	}
+}
+
+// WithBackup enables or disables the backup before each update using manager
+func WithBackup(enabled bool, manager BackupProvider) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.BackupEnabled = enabled
+		cfg.BackupManager = manager
+	}
+}
+
+// WithFilter sets the filter deciding which repositories are updated
+func WithFilter(repoFilter RepositoryFilter) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.Filter = repoFilter
+	}
+}
+
+// WithCommitMessageTemplate sets the template of the merge commits created by git pull
+func WithCommitMessageTemplate(messageTemplate string) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.CommitMessageTemplate = messageTemplate
+	}
+}
+
+// WithFetchDepth sets the depth of the fetch executed before the pull
+func WithFetchDepth(depth int) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.FetchDepth = depth
+	}
+}
+
+// WithCommitIdentity sets the author and committer of the commits and tags created by updateGit.
+// Empty values keep the identity of the git configuration.
+func WithCommitIdentity(name, email string) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.Identity = CommitIdentity{Name: name, Email: email}
+	}
+}
+
+// WithSigning enables or disables the GPG signing of the merge commits created by git pull with keyID
+func WithSigning(enabled bool, keyID string) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.Signing = SigningOptions{Enabled: enabled, KeyID: keyID}
+	}
+}
+
+// WithTag creates a tag after each successful pull. An empty opts.Name disables it.
+func WithTag(opts TagOptions) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.Tag = opts
+	}
+}
+
+// WithSubmodules updates the submodules after each successful pull when opts.Enabled is true
+func WithSubmodules(opts SubmoduleOptions) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.Submodules = opts
+	}
+}
+
+// WithPullOnlyIfBehind skips the pull of repositories whose upstream has no new commits
+func WithPullOnlyIfBehind(enabled bool) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.PullOnlyIfBehind = enabled
+	}
+}
+
+// WithAutoStash stashes the uncommitted changes of each repository before the pull and restores them after it
+func WithAutoStash(enabled bool) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.AutoStash = enabled
+	}
+}
+
+// WithPostPullDiffStat prints and records the diff stat of the commits added by each pull
+func WithPostPullDiffStat(enabled bool) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.PostPullDiffStat = enabled
+	}
+}
+
+// WithFetchAllRemotes fetches every remote of each repository before the pull
+func WithFetchAllRemotes(enabled bool) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.FetchAllRemotes = enabled
+	}
+}
+
+// WithFetchBeforePull runs git fetch --all in each repository before the pull
+func WithFetchBeforePull(enabled bool) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.FetchBeforePull = enabled
+	}
+}
+
+// WithFetchPrune removes the remote-tracking branches deleted from the remotes when FetchAllRemotes or FetchBeforePull is enabled
+func WithFetchPrune(enabled bool) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.FetchPrune = enabled
+	}
+}
+
+// WithPruneWorktrees removes stale worktree references after each successful pull
+func WithPruneWorktrees(enabled bool) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.PruneWorktrees = enabled
+	}
+}
+
+// WithOnRepoStart sets the function called before the update of each repository
+func WithOnRepoStart(fn func(repo Repository)) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.OnRepoStart = fn
+	}
+}
+
+// WithOnRepoComplete sets the function called with the result of each repository after its update
+func WithOnRepoComplete(fn func(repo Repository, result RepoResult)) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.OnRepoComplete = fn
+	}
+}
+
+// WithDryRun only finds and filters the repositories, without running the backup and the pull
+func WithDryRun(enabled bool) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.DryRun = enabled
+	}
+}
+
+// WithCommandFactory sets the function that creates the git pull command, e.g. a fake git in tests
+func WithCommandFactory(factory CommandFactory) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.CommandFactory = factory
+	}
+}
+
+// WithPullStrategy sets how git pull integrates the upstream commits (merge, rebase or ff-only)
+func WithPullStrategy(strategy PullStrategy) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.PullStrategy = strategy
+	}
+}
+
+// WithExtraPullArgs sets the arguments appended to the git pull command line
+func WithExtraPullArgs(args ...string) UpdateOption {
+	return func(cfg *UpdateConfig) {
+		cfg.ExtraPullArgs = args
+	}
+}
@@ -0,0 +1,60 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aeciopires/updateGit/internal/common"
+)
+
+// autoStashRef is the stash entry created by stashChanges. The repository is not changed by
+// other git commands of updateGit between the push and the pop, so it is always the last entry
+const autoStashRef = "stash@{0}"
+
+// HasUncommittedChanges reports whether the repository has staged, unstaged or untracked changes.
+// When git status fails it assumes there are changes.
+func HasUncommittedChanges(repoPath string) bool {
+	// Without optional locks git status does not refresh the index, so the repository is not written
+	cmd := newGitCommand(repoPath, "--no-optional-locks", "status", "--porcelain")
+	output, err := cmd.Output()
+	if err != nil {
+		common.Logger("warning", "Failed to detect repository status, assuming changes exist. repository=%s error=%v", repoPath, err)
+		return true
+	}
+	return len(output) > 0
+}
+
+// stashChanges saves the uncommitted changes, including untracked files, in a stash entry with the message
+func stashChanges(repoPath, message string, identity CommitIdentity) error {
+	// The stash entry is a commit, so git needs the identity of the committer
+	cmd := newGitCommandWithConfig(repoPath, commitConfigEntries(identity, SigningOptions{}), "stash", "push", "--include-untracked", "-m", message)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return &GitError{
+			Repository: repoPath,
+			Operation:  "stash push",
+			Err:        fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output))),
+		}
+	}
+	return nil
+}
+
+// popStashedChanges applies and drops the stash entry of stashChanges. When the changes conflict with
+// the pulled commits, the working tree is reset to HEAD and the entry is kept, so no change is lost.
+func popStashedChanges(repoPath string) error {
+	cmd := newGitCommand(repoPath, "stash", "pop", autoStashRef)
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+
+	reset := newGitCommand(repoPath, "reset", "--hard", "--quiet", "HEAD")
+	if resetOutput, resetErr := reset.CombinedOutput(); resetErr != nil {
+		common.Logger("error", "Could not reset the working tree after the failed stash pop. repository=%s error=%v output=%s", repoPath, resetErr, strings.TrimSpace(string(resetOutput)))
+	}
+
+	return &GitError{
+		Repository: repoPath,
+		Operation:  "stash pop",
+		Err:        fmt.Errorf("%v: %s. The changes are kept in %s", err, strings.TrimSpace(string(output)), autoStashRef),
+	}
+}
@@ -0,0 +1,43 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SubmoduleOptions holds the options of the submodule update executed after a successful pull
+type SubmoduleOptions struct {
+	// Enabled runs git submodule update --init --recursive
+	Enabled bool
+	// Jobs is the number of submodules fetched in parallel. 0 keeps the git default (submodule.fetchJobs)
+	Jobs int
+	// Depth limits the history fetched for each submodule. 0 means not limited
+	Depth int
+}
+
+// args returns the arguments of git submodule update for the options
+func (o SubmoduleOptions) args() []string {
+	args := []string{"submodule", "update", "--init", "--recursive"}
+	if o.Jobs > 0 {
+		args = append(args, "--jobs="+strconv.Itoa(o.Jobs))
+	}
+	if o.Depth > 0 {
+		args = append(args, "--depth="+strconv.Itoa(o.Depth))
+	}
+	return args
+}
+
+// UpdateSubmodules initializes and updates the submodules of the repository recursively
+// to the commits recorded in HEAD. Jobs and Depth of opts are passed to git; Enabled is not checked.
+func UpdateSubmodules(repoPath string, opts SubmoduleOptions) error {
+	cmd := newGitCommand(repoPath, opts.args()...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return &GitError{
+			Repository: repoPath,
+			Operation:  "submodule update",
+			Err:        fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output))),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/aeciopires/updateGit/internal/common"
+)
+
+// DefaultTagMessage is the message of the tags created after pull when no message is informed
+const DefaultTagMessage = "Synchronized by updateGit"
+
+// TagOptions holds the options of the tag created after a successful pull
+type TagOptions struct {
+	// Name of the tag. It is a text/template executed with the Repository, e.g. "sync-{{.CurrentBranch}}"
+	Name string
+	// Message of the annotated tag, also a text/template. Empty means DefaultTagMessage
+	Message string
+	// Push sends the tag to the origin remote
+	Push bool
+	// Force replaces the tag if it already exists
+	Force bool
+}
+
+// TagExists checks if the tag exists in the repository
+func TagExists(repoPath, tagName string) bool {
+	cmd := newGitCommand(repoPath, "rev-parse", "--quiet", "--verify", "refs/tags/"+tagName)
+	return cmd.Run() == nil
+}
+
+// CreateTag creates an annotated tag pointing to HEAD, tagged by identity. With force an existing tag is replaced.
+func CreateTag(repoPath, tagName, message string, force bool, identity CommitIdentity) error {
+	args := []string{"tag", "-a", tagName, "-m", message}
+	if force {
+		args = append(args, "--force")
+	}
+
+	// Annotated tags need the identity of the tagger
+	cmd := newGitCommandWithConfig(repoPath, commitConfigEntries(identity, SigningOptions{}), args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return &GitError{
+			Repository: repoPath,
+			Operation:  "tag",
+			Err:        fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output))),
+		}
+	}
+
+	return nil
+}
+
+// PushTag sends the tag to the origin remote. With force the remote tag is replaced.
+func PushTag(repoPath, tagName string, force bool) error {
+	args := []string{"push", "origin", "refs/tags/" + tagName}
+	if force {
+		args = append(args, "--force")
+	}
+
+	cmd := newGitCommand(repoPath, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return &GitError{
+			Repository: repoPath,
+			Operation:  "push tag",
+			Err:        fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output))),
+		}
+	}
+
+	return nil
+}
+
+// renderRepositoryTemplate executes the template text with the repository data
+func renderRepositoryTemplate(name, text string, repo Repository) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", name, err)
+	}
+
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, repo); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+
+	return rendered.String(), nil
+}
+
+// tagRepository creates the tag of opts after a successful pull and pushes it if requested.
+// Errors are logged, they do not change the result of the pull.
+func tagRepository(repo Repository, opts TagOptions, identity CommitIdentity) {
+	tagName, err := renderRepositoryTemplate("tag name", opts.Name, repo)
+	if err != nil {
+		common.Logger("error", "Could not create tag name. repository=%s error=%v", repo.Name, err)
+		return
+	}
+
+	messageTemplate := opts.Message
+	if messageTemplate == "" {
+		messageTemplate = DefaultTagMessage
+	}
+	message, err := renderRepositoryTemplate("tag message", messageTemplate, repo)
+	if err != nil {
+		common.Logger("error", "Could not create tag message. repository=%s error=%v", repo.Name, err)
+		return
+	}
+
+	if !opts.Force && TagExists(repo.Path, tagName) {
+		common.Logger("warning", "Tag already exists, skipping it. Use --git-force-tag to replace it. repository=%s tag=%s", repo.Name, tagName)
+		return
+	}
+
+	if err := CreateTag(repo.Path, tagName, message, opts.Force, identity); err != nil {
+		common.Logger("error", "Could not create tag. repository=%s tag=%s error=%v", repo.Name, tagName, err)
+		return
+	}
+	common.Logger("info", "Tag created. repository=%s tag=%s", repo.Name, tagName)
+
+	if !opts.Push {
+		return
+	}
+	if err := PushTag(repo.Path, tagName, opts.Force); err != nil {
+		common.Logger("error", "Could not push tag. repository=%s tag=%s error=%v", repo.Name, tagName, err)
+		return
+	}
+	common.Logger("info", "Tag pushed to origin. repository=%s tag=%s", repo.Name, tagName)
+}
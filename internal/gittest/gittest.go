@@ -0,0 +1,75 @@
+// Package gittest creates the git repositories used by the tests of the other packages
+package gittest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aeciopires/updateGit/internal/config"
+)
+
+// Run executes a git command in dir with a fixed identity and returns its output.
+// The test fails if the command fails.
+func Run(t testing.TB, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=updateGit test",
+		"GIT_AUTHOR_EMAIL=test@updategit.local",
+		"GIT_COMMITTER_NAME=updateGit test",
+		"GIT_COMMITTER_EMAIL=test@updategit.local",
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return string(output)
+}
+
+// CommitFile creates or changes a file in the repository and commits it
+func CommitFile(t testing.TB, repoDir, fileName, content, message string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(repoDir, fileName), []byte(content), config.PermissionFile); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+	Run(t, repoDir, "add", fileName)
+	Run(t, repoDir, "commit", "-m", message)
+}
+
+// InitRepository creates a git repository in dir on branch main with one commit
+func InitRepository(t testing.TB, dir string) {
+	t.Helper()
+
+	Run(t, dir, "init", "-b", "main")
+	CommitFile(t, dir, "README.md", "# test", "initial commit")
+}
+
+// NewRemote creates a bare repository playing the role of the remote, with the commit of
+// InitRepository on branch main. It returns the path of the bare repository and of a clone
+// used to push new commits to it. Both are removed when the test finishes.
+func NewRemote(t testing.TB) (remote, seed string) {
+	t.Helper()
+
+	workDir := t.TempDir()
+	remote = filepath.Join(workDir, "project.git")
+	seed = filepath.Join(workDir, "seed")
+	Run(t, workDir, "init", "--bare", "-b", "main", remote)
+	Run(t, workDir, "clone", remote, seed)
+	InitRepository(t, seed)
+	Run(t, seed, "push", "origin", "HEAD:main")
+	return remote, seed
+}
+
+// Clone clones remote into dir with the given name and returns the path of the clone
+func Clone(t testing.TB, remote, dir, name string) string {
+	t.Helper()
+
+	Run(t, dir, "clone", remote, name)
+	return filepath.Join(dir, name)
+}
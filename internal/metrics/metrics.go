@@ -0,0 +1,236 @@
+// Package metrics provides an in-process registry of counters, gauges and histograms.
+// The values are kept in memory and can be read by tests, subcommands or exporters.
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Names of the metrics registered in the Default registry
+const (
+	ReposDiscoveredName = "repos_discovered"
+	ReposUpdatedName    = "repos_updated"
+	ReposFailedName     = "repos_failed"
+	BackupCreatedName   = "backup_created"
+)
+
+// Counter is a value that only increases
+type Counter struct {
+	value atomic.Int64
+}
+
+// Inc increments the counter by 1
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Add increments the counter by n. Negative values are ignored
+func (c *Counter) Add(n int64) {
+	if n > 0 {
+		c.value.Add(n)
+	}
+}
+
+// Value returns the current value of the counter
+func (c *Counter) Value() int64 {
+	return c.value.Load()
+}
+
+// Gauge is a value that can increase and decrease
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+// Set replaces the value of the gauge
+func (g *Gauge) Set(value float64) {
+	g.bits.Store(math.Float64bits(value))
+}
+
+// Add adds delta to the gauge. Use a negative delta to decrease it
+func (g *Gauge) Add(delta float64) {
+	for {
+		old := g.bits.Load()
+		updated := math.Float64bits(math.Float64frombits(old) + delta)
+		if g.bits.CompareAndSwap(old, updated) {
+			return
+		}
+	}
+}
+
+// Value returns the current value of the gauge
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+// DefaultBuckets are the upper bounds used by histograms created without buckets,
+// suitable for durations in seconds
+var DefaultBuckets = []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300}
+
+// Histogram counts observed values in buckets with cumulative upper bounds
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+// HistogramSnapshot is a copy of the values of a histogram
+type HistogramSnapshot struct {
+	// Buckets maps each upper bound to the number of observations less than or equal to it
+	Buckets map[float64]uint64
+	Count   uint64
+	Sum     float64
+}
+
+// newHistogram creates a histogram with sorted buckets
+func newHistogram(buckets []float64) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	return &Histogram{
+		buckets: sorted,
+		counts:  make([]uint64, len(sorted)),
+	}
+}
+
+// Observe adds a value to the histogram
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// Snapshot returns a copy of the current values of the histogram
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	snapshot := HistogramSnapshot{
+		Buckets: make(map[float64]uint64, len(h.buckets)),
+		Count:   h.count,
+		Sum:     h.sum,
+	}
+	for i, bound := range h.buckets {
+		snapshot.Buckets[bound] = h.counts[i]
+	}
+
+	return snapshot
+}
+
+// Registry holds metrics by name
+type Registry struct {
+	mu         sync.Mutex
+	counters   map[string]*Counter
+	gauges     map[string]*Gauge
+	histograms map[string]*Histogram
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		counters:   make(map[string]*Counter),
+		gauges:     make(map[string]*Gauge),
+		histograms: make(map[string]*Histogram),
+	}
+}
+
+// Counter returns the counter with the name, creating it if it does not exist
+func (r *Registry) Counter(name string) *Counter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counter, ok := r.counters[name]
+	if !ok {
+		counter = &Counter{}
+		r.counters[name] = counter
+	}
+	return counter
+}
+
+// Gauge returns the gauge with the name, creating it if it does not exist
+func (r *Registry) Gauge(name string) *Gauge {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	gauge, ok := r.gauges[name]
+	if !ok {
+		gauge = &Gauge{}
+		r.gauges[name] = gauge
+	}
+	return gauge
+}
+
+// Histogram returns the histogram with the name, creating it with buckets if it does not exist.
+// The buckets of an existing histogram are not changed. Empty buckets means DefaultBuckets
+func (r *Registry) Histogram(name string, buckets ...float64) *Histogram {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	histogram, ok := r.histograms[name]
+	if !ok {
+		histogram = newHistogram(buckets)
+		r.histograms[name] = histogram
+	}
+	return histogram
+}
+
+// Counters returns the current value of all counters by name
+func (r *Registry) Counters() map[string]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	values := make(map[string]int64, len(r.counters))
+	for name, counter := range r.counters {
+		values[name] = counter.Value()
+	}
+	return values
+}
+
+// Gauges returns the current value of all gauges by name
+func (r *Registry) Gauges() map[string]float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	values := make(map[string]float64, len(r.gauges))
+	for name, gauge := range r.gauges {
+		values[name] = gauge.Value()
+	}
+	return values
+}
+
+// Histograms returns a snapshot of all histograms by name
+func (r *Registry) Histograms() map[string]HistogramSnapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	values := make(map[string]HistogramSnapshot, len(r.histograms))
+	for name, histogram := range r.histograms {
+		values[name] = histogram.Snapshot()
+	}
+	return values
+}
+
+// Default is the registry used by updateGit
+var Default = NewRegistry()
+
+// Counters of the repository updates, registered in the Default registry
+var (
+	ReposDiscovered = Default.Counter(ReposDiscoveredName)
+	ReposUpdated    = Default.Counter(ReposUpdatedName)
+	ReposFailed     = Default.Counter(ReposFailedName)
+	BackupCreated   = Default.Counter(BackupCreatedName)
+)
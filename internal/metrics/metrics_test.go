@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.Counter("test")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counter.Inc()
+		}()
+	}
+	wg.Wait()
+	counter.Add(5)
+	counter.Add(-3)
+
+	if got := counter.Value(); got != 105 {
+		t.Errorf("Value() = %d, want 105", got)
+	}
+	if registry.Counter("test") != counter {
+		t.Error("Counter() returned a new counter for an existing name")
+	}
+	if got := registry.Counters()["test"]; got != 105 {
+		t.Errorf("Counters()[test] = %d, want 105", got)
+	}
+}
+
+func TestGauge(t *testing.T) {
+	gauge := NewRegistry().Gauge("test")
+
+	gauge.Set(10)
+	gauge.Add(2.5)
+	gauge.Add(-5)
+
+	if got := gauge.Value(); got != 7.5 {
+		t.Errorf("Value() = %v, want 7.5", got)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	registry := NewRegistry()
+	histogram := registry.Histogram("test", 10, 1, 5)
+
+	for _, value := range []float64{0.5, 1, 3, 7, 20} {
+		histogram.Observe(value)
+	}
+
+	snapshot := registry.Histograms()["test"]
+	expected := map[float64]uint64{1: 2, 5: 3, 10: 4}
+	for bound, count := range expected {
+		if snapshot.Buckets[bound] != count {
+			t.Errorf("Buckets[%v] = %d, want %d", bound, snapshot.Buckets[bound], count)
+		}
+	}
+	if snapshot.Count != 5 {
+		t.Errorf("Count = %d, want 5", snapshot.Count)
+	}
+	if snapshot.Sum != 31.5 {
+		t.Errorf("Sum = %v, want 31.5", snapshot.Sum)
+	}
+
+	if len(NewRegistry().Histogram("default").Snapshot().Buckets) != len(DefaultBuckets) {
+		t.Error("histogram without buckets should use DefaultBuckets")
+	}
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	counters := Default.Counters()
+	for _, name := range []string{ReposDiscoveredName, ReposUpdatedName, ReposFailedName, BackupCreatedName} {
+		if _, ok := counters[name]; !ok {
+			t.Errorf("counter %s is not registered in the Default registry", name)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package update
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aeciopires/updateGit/internal/common"
+	"github.com/aeciopires/updateGit/internal/config"
+)
+
+// updateLockMaxAge is the age after which a lock file is considered left behind by an interrupted update
+const updateLockMaxAge = 5 * time.Minute
+
+// updateLockPath returns the path of the lock file protecting the replacement of executablePath
+func updateLockPath(executablePath string) string {
+	return filepath.Join(filepath.Dir(executablePath), "."+config.CLIName+".lock")
+}
+
+// acquireUpdateLock creates the lock file with the PID of this process.
+// The file is created with O_EXCL, so only one instance gets the lock.
+// A lock older than updateLockMaxAge is removed and the creation is tried again.
+// It returns a function that removes the lock file.
+func acquireUpdateLock(lockPath string) (func(), error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		lockFile, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, config.PermissionFile)
+		if err == nil {
+			fmt.Fprintf(lockFile, "%d\n", os.Getpid())
+			lockFile.Close()
+			common.Logger("debug", "Update lock acquired. path=%s", lockPath)
+
+			return func() {
+				if err := os.Remove(lockPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+					common.Logger("warning", "Could not remove update lock %s: %v", lockPath, err)
+				}
+			}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("could not create update lock %s: %w", lockPath, err)
+		}
+
+		info, statErr := os.Stat(lockPath)
+		if statErr != nil {
+			// The other instance removed the lock in the meantime
+			continue
+		}
+		if age := time.Since(info.ModTime()); age < updateLockMaxAge {
+			return nil, fmt.Errorf("another update is in progress (pid %s, lock file %s created %v ago)", readLockPID(lockPath), lockPath, age.Round(time.Second))
+		}
+
+		common.Logger("warning", "Removing stale update lock %s", lockPath)
+		if err := os.Remove(lockPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("could not remove stale update lock %s: %w", lockPath, err)
+		}
+	}
+
+	return nil, fmt.Errorf("another update is in progress (lock file %s)", lockPath)
+}
+
+// readLockPID returns the PID stored in the lock file or "unknown"
+func readLockPID(lockPath string) string {
+	content, err := os.ReadFile(lockPath)
+	if err != nil {
+		return "unknown"
+	}
+	pid := strings.TrimSpace(string(content))
+	if _, err := strconv.Atoi(pid); err != nil {
+		return "unknown"
+	}
+	return pid
+}
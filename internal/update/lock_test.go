@@ -0,0 +1,79 @@
+package update
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aeciopires/updateGit/internal/config"
+)
+
+func TestAcquireUpdateLock(t *testing.T) {
+	lockPath := updateLockPath(filepath.Join(t.TempDir(), config.CLIName))
+
+	release, err := acquireUpdateLock(lockPath)
+	if err != nil {
+		t.Fatalf("acquireUpdateLock returned error: %v", err)
+	}
+
+	// A second instance is rejected while the lock is held
+	if _, err := acquireUpdateLock(lockPath); err == nil {
+		t.Fatal("expected the second lock to be rejected")
+	} else if !strings.Contains(err.Error(), fmt.Sprintf("pid %d", os.Getpid())) {
+		t.Errorf("expected the PID of the holder in the error, got %v", err)
+	}
+
+	release()
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Fatalf("expected the lock to be removed, got %v", err)
+	}
+
+	// The lock can be taken again once released
+	release, err = acquireUpdateLock(lockPath)
+	if err != nil {
+		t.Fatalf("expected the released lock to be acquired again: %v", err)
+	}
+	release()
+}
+
+func TestAcquireUpdateLockStale(t *testing.T) {
+	tests := []struct {
+		name      string
+		age       time.Duration
+		expectErr bool
+	}{
+		{name: "recent lock", age: time.Minute, expectErr: true},
+		{name: "stale lock", age: updateLockMaxAge + time.Minute, expectErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lockPath := updateLockPath(filepath.Join(t.TempDir(), config.CLIName))
+			if err := os.WriteFile(lockPath, []byte("not a pid\n"), config.PermissionFile); err != nil {
+				t.Fatalf("could not write lock file: %v", err)
+			}
+			modTime := time.Now().Add(-tt.age)
+			if err := os.Chtimes(lockPath, modTime, modTime); err != nil {
+				t.Fatalf("could not change the lock time: %v", err)
+			}
+
+			release, err := acquireUpdateLock(lockPath)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("expected the lock to be rejected")
+				}
+				if !strings.Contains(err.Error(), "pid unknown") {
+					t.Errorf("expected an unknown PID in the error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected the stale lock to be replaced: %v", err)
+			}
+			release()
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/aeciopires/updateGit/internal/config"
 	"github.com/aeciopires/updateGit/internal/common"
@@ -19,6 +20,13 @@ import (
 
 // Package-level variables.
 
+// githubAPIURL is the base URL of the GitHub API. Tests point it to a fake server.
+var githubAPIURL = "https://api.github.com"
+
+// currentExecutable returns the path of the executable replaced by ApplyUpdate.
+// Tests replace it to update a temporary file instead of the test binary.
+var currentExecutable = os.Executable
+
 // GitHubReleaseAsset represents an asset in a GitHub release.
 type GitHubReleaseAsset struct {
 	Name        string `json:"name"`
@@ -27,17 +35,59 @@ type GitHubReleaseAsset struct {
 
 // GitHubRelease represents a GitHub release.
 type GitHubRelease struct {
-	TagName string               `json:"tag_name"`
-	Assets  []GitHubReleaseAsset `json:"assets"`
+	TagName     string               `json:"tag_name"`
+	PublishedAt time.Time            `json:"published_at"`
+	Prerelease  bool                 `json:"prerelease"`
+	Body        string               `json:"body"` // Release notes in markdown
+	Assets      []GitHubReleaseAsset `json:"assets"`
 }
 
-// CheckForUpdate checks for a new version of the application on GitHub.
+// releaseNotesSummaryLength is the number of characters of the release notes shown in the update prompt
+const releaseNotesSummaryLength = 200
+
+// releaseCache is the content of the file storing the last release check
+type releaseCache struct {
+	Repository string        `json:"repository"`
+	CheckedAt  time.Time     `json:"checked_at"`
+	Release    GitHubRelease `json:"release"`
+}
+
+// CheckForUpdate checks for a new version of the application on GitHub using the given HTTP client.
 // It returns the release info if an update is available, otherwise nil.
-func CheckForUpdate(repo string) *GitHubRelease {
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", repo)
+func CheckForUpdate(client *http.Client, repo string) *GitHubRelease {
+	return CheckForUpdateCached(client, repo, 0)
+}
+
+// CheckForUpdateCached works like CheckForUpdate, but reuses the result of the last check
+// stored in ~/.cache/updateGit/release-check.json when it is younger than cacheTTL.
+// A cacheTTL equal to 0 disables the cache.
+func CheckForUpdateCached(client *http.Client, repo string, cacheTTL time.Duration) *GitHubRelease {
+	release, found := loadCachedRelease(repo, cacheTTL)
+	if !found {
+		release = fetchLatestRelease(client, repo)
+		if cacheTTL > 0 {
+			saveCachedRelease(repo, release)
+		}
+	}
+
+	latestVersion := release.TagName
+	currentVersion := config.CLIVersion
+
+	common.Logger("info", "Current version: %s, Latest version on GitHub: %s", currentVersion, latestVersion)
+
+	if currentVersion != latestVersion {
+		return &release
+	}
+
+	return nil // No update available
+}
+
+// fetchLatestRelease gets the latest release of repo from the GitHub API
+func fetchLatestRelease(client *http.Client, repo string) GitHubRelease {
+	apiURL := fmt.Sprintf("%s/repos/%s/releases/latest", githubAPIURL, repo)
 	common.Logger("debug", "Checking for updates at: %s", apiURL)
 
-	resp, err := http.Get(apiURL)
+	resp, err := client.Get(apiURL)
 	if err != nil {
 		common.Logger("fatal", "Failed to fetch latest release from GitHub %s: %w", apiURL, err)
 	}
@@ -52,27 +102,123 @@ func CheckForUpdate(repo string) *GitHubRelease {
 		common.Logger("fatal", "Failed to parse GitHub release JSON: %w", err)
 	}
 
-	latestVersion := release.TagName
-	currentVersion := config.CLIVersion
+	return release
+}
 
-	common.Logger("info", "Current version: %s, Latest version on GitHub: %s", currentVersion, latestVersion)
+// ListReleases returns the most recent releases of repo published on GitHub, including pre-releases.
+func ListReleases(client *http.Client, repo string) ([]GitHubRelease, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/releases", githubAPIURL, repo)
+	common.Logger("debug", "Listing releases at: %s", apiURL)
 
-	if currentVersion != latestVersion {
-		return &release
+	resp, err := client.Get(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list releases from GitHub %s: %w", apiURL, err)
 	}
+	defer resp.Body.Close()
 
-	return nil // No update available
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list releases from %s: GitHub API returned status %s", apiURL, resp.Status)
+	}
+
+	var releases []GitHubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub releases JSON: %w", err)
+	}
+
+	return releases, nil
 }
 
-// ApplyUpdate downloads and applies a new binary from a GitHub release.
-func ApplyUpdate(release *GitHubRelease) {
+// ReleaseNotesSummary returns the first 200 characters of the release notes in a single line
+func ReleaseNotesSummary(release *GitHubRelease) string {
+	notes := strings.Join(strings.Fields(release.Body), " ")
+
+	runes := []rune(notes)
+	if len(runes) > releaseNotesSummaryLength {
+		return string(runes[:releaseNotesSummaryLength]) + "..."
+	}
+	return notes
+}
+
+// releaseCachePath returns the path of the release check cache file
+func releaseCachePath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, config.CLIName, "release-check.json"), nil
+}
+
+// loadCachedRelease returns the cached release of repo if it is younger than cacheTTL
+func loadCachedRelease(repo string, cacheTTL time.Duration) (GitHubRelease, bool) {
+	if cacheTTL <= 0 {
+		return GitHubRelease{}, false
+	}
+
+	cachePath, err := releaseCachePath()
+	if err != nil {
+		common.Logger("debug", "Could not determine release cache path: %v", err)
+		return GitHubRelease{}, false
+	}
+
+	content, err := os.ReadFile(cachePath)
+	if err != nil {
+		common.Logger("debug", "Release cache not available. path=%s error=%v", cachePath, err)
+		return GitHubRelease{}, false
+	}
+
+	var cache releaseCache
+	if err := json.Unmarshal(content, &cache); err != nil {
+		common.Logger("debug", "Ignoring invalid release cache. path=%s error=%v", cachePath, err)
+		return GitHubRelease{}, false
+	}
+
+	age := time.Since(cache.CheckedAt)
+	if cache.Repository != repo || age < 0 || age > cacheTTL {
+		common.Logger("debug", "Release cache expired. path=%s age=%v ttl=%v", cachePath, age, cacheTTL)
+		return GitHubRelease{}, false
+	}
+
+	common.Logger("debug", "Using cached release check. path=%s age=%v", cachePath, age.Round(time.Second))
+	return cache.Release, true
+}
+
+// saveCachedRelease stores the release check result. Failures are not fatal, the cache is optional.
+func saveCachedRelease(repo string, release GitHubRelease) {
+	cachePath, err := releaseCachePath()
+	if err != nil {
+		common.Logger("debug", "Could not determine release cache path: %v", err)
+		return
+	}
+
+	content, err := json.Marshal(releaseCache{Repository: repo, CheckedAt: time.Now(), Release: release})
+	if err != nil {
+		common.Logger("debug", "Could not encode release cache: %v", err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), config.PermissionDir); err != nil {
+		common.Logger("debug", "Could not create release cache directory: %v", err)
+		return
+	}
+	if err := os.WriteFile(cachePath, content, config.PermissionFile); err != nil {
+		common.Logger("debug", "Could not write release cache. path=%s error=%v", cachePath, err)
+	}
+}
+
+// UpdatePlan describes the binary that ApplyUpdate would download
+type UpdatePlan struct {
+	Version          string
+	AssetName        string
+	DownloadURL      string
+	ExpectedChecksum string
+}
+
+// findReleaseAssets returns the binary asset for the current platform and the checksums.txt asset
+func findReleaseAssets(release *GitHubRelease) (binaryAsset, checksumsAsset *GitHubReleaseAsset, err error) {
 	// Determine the asset name based on OS and architecture
 	assetName := fmt.Sprintf("%s-%s-%s", config.CLIName, runtime.GOOS, runtime.GOARCH)
 	common.Logger("debug", "Looking for asset: %s", assetName)
 
-	var binaryAsset *GitHubReleaseAsset
-	var checksumsAsset *GitHubReleaseAsset
-
 	for i, asset := range release.Assets {
 		if asset.Name == assetName {
 			binaryAsset = &release.Assets[i]
@@ -83,12 +229,49 @@ func ApplyUpdate(release *GitHubRelease) {
 	}
 
 	if binaryAsset == nil {
-		common.Logger("fatal", "Could not find a release asset for your platform (%s/%s)", runtime.GOOS, runtime.GOARCH)
+		return nil, nil, fmt.Errorf("could not find a release asset for your platform (%s/%s)", runtime.GOOS, runtime.GOARCH)
 	}
 	if checksumsAsset == nil {
-		common.Logger("fatal", "Could not find checksums.txt in the release assets")
+		return nil, nil, fmt.Errorf("could not find checksums.txt in the release assets")
+	}
+
+	return binaryAsset, checksumsAsset, nil
+}
+
+// PlanUpdate returns the download URL and the expected checksum of the binary for the current platform.
+// Only checksums.txt is downloaded, the binary is not downloaded and nothing is replaced.
+func PlanUpdate(release *GitHubRelease) (*UpdatePlan, error) {
+	binaryAsset, checksumsAsset, err := findReleaseAssets(release)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums, err := DownloadFile(checksumsAsset.DownloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download checksums: %w", err)
 	}
 
+	expectedChecksum, err := ParseChecksum(string(checksums), config.CLICheckSumBinDir+binaryAsset.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UpdatePlan{
+		Version:          release.TagName,
+		AssetName:        binaryAsset.Name,
+		DownloadURL:      binaryAsset.DownloadURL,
+		ExpectedChecksum: expectedChecksum,
+	}, nil
+}
+
+// ApplyUpdate downloads and applies a new binary from a GitHub release.
+func ApplyUpdate(release *GitHubRelease) {
+	binaryAsset, checksumsAsset, err := findReleaseAssets(release)
+	if err != nil {
+		common.Logger("fatal", "%v", err)
+	}
+	assetName := binaryAsset.Name
+
 	common.Logger("info", "Downloading checksums from %s...", checksumsAsset.DownloadURL)
 	checksums, err := DownloadFile(checksumsAsset.DownloadURL)
 	if err != nil {
@@ -120,11 +303,18 @@ func ApplyUpdate(release *GitHubRelease) {
 	common.Logger("info", "Checksum verified successfully.")
 
 	// Replace the current executable
-	executablePath, err := os.Executable()
+	executablePath, err := currentExecutable()
 	if err != nil {
 		common.Logger("fatal", "Could not determine executable path: %w", err)
 	}
 
+	// Only one instance can replace the executable at a time
+	releaseLock, err := acquireUpdateLock(updateLockPath(executablePath))
+	if err != nil {
+		common.Logger("fatal", "%v", err)
+	}
+	defer releaseLock()
+
 	// Create a temporary file with the new binary content
 	tmpFile, err := os.CreateTemp(filepath.Dir(executablePath), "update-*.tmp")
 	if err != nil {
@@ -159,19 +349,55 @@ func ApplyUpdate(release *GitHubRelease) {
 	common.Logger("info", "Update successful! The old binary is at %s. It can be removed manually.", oldPath)
 }
 
+// Retries of DownloadFile after a network error or a 5xx response
+const (
+	DefaultDownloadMaxRetries   = 3
+	DefaultDownloadInitialDelay = 2 * time.Second
+)
+
 // DownloadFile is a helper to download a file from a URL.
+// Transient failures are retried with DefaultDownloadMaxRetries and DefaultDownloadInitialDelay.
 func DownloadFile(url string) ([]byte, error) {
+	return DownloadFileWithRetry(url, DefaultDownloadMaxRetries, DefaultDownloadInitialDelay)
+}
+
+// DownloadFileWithRetry downloads a file from a URL, retrying up to maxRetries times after a network
+// error or a 5xx response. The delay starts at initialDelay and doubles after each attempt.
+// Other responses, like 4xx, fail immediately.
+func DownloadFileWithRetry(url string, maxRetries int, initialDelay time.Duration) ([]byte, error) {
+	delay := initialDelay
+	for attempt := 1; ; attempt++ {
+		data, retryable, err := downloadFileOnce(url)
+		if err == nil {
+			return data, nil
+		}
+		if !retryable || attempt > maxRetries {
+			return nil, err
+		}
+
+		common.Logger("warning", "Download failed, retrying. attempt=%d max_retries=%d delay=%s url=%s error=%v", attempt, maxRetries, delay, url, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// downloadFileOnce downloads a file from a URL and reports whether a failure can be retried
+func downloadFileOnce(url string) ([]byte, bool, error) {
 	resp, err := http.Get(url)
 	if err != nil {
-		return nil, err
+		return nil, true, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("bad status: %s", resp.Status)
+		return nil, resp.StatusCode >= http.StatusInternalServerError, fmt.Errorf("bad status: %s", resp.Status)
 	}
 
-	return io.ReadAll(resp.Body)
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+	return data, false, nil
 }
 
 // ParseChecksum finds the checksum for a specific file from the checksums.txt content.
@@ -0,0 +1,245 @@
+package update
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/aeciopires/updateGit/internal/config"
+)
+
+// newFakeGitHubServer starts a server answering the latest release endpoint with release
+// and points the package to it while the test runs
+func newFakeGitHubServer(t *testing.T, release GitHubRelease) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/aeciopires/updateGit/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(release)
+	}))
+	t.Cleanup(server.Close)
+
+	oldURL := githubAPIURL
+	githubAPIURL = server.URL
+	t.Cleanup(func() { githubAPIURL = oldURL })
+
+	return server
+}
+
+func TestCheckForUpdate(t *testing.T) {
+	t.Run("new version available", func(t *testing.T) {
+		server := newFakeGitHubServer(t, GitHubRelease{
+			TagName: "99.0.0",
+			Assets:  []GitHubReleaseAsset{{Name: "checksums.txt", DownloadURL: "https://example.com/checksums.txt"}},
+		})
+
+		release := CheckForUpdate(server.Client(), "aeciopires/updateGit")
+		if release == nil {
+			t.Fatal("expected a release, got nil")
+		}
+		if release.TagName != "99.0.0" || len(release.Assets) != 1 {
+			t.Errorf("unexpected release: %+v", release)
+		}
+	})
+
+	t.Run("already on the latest version", func(t *testing.T) {
+		server := newFakeGitHubServer(t, GitHubRelease{TagName: config.CLIVersion})
+
+		if release := CheckForUpdate(server.Client(), "aeciopires/updateGit"); release != nil {
+			t.Errorf("expected nil, got %+v", release)
+		}
+	})
+}
+
+func TestParseChecksum(t *testing.T) {
+	const checksum = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	const otherChecksum = "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752"
+
+	tests := []struct {
+		name      string
+		content   string
+		fileName  string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "exact match",
+			content:  checksum + "  bin/updateGit-linux-amd64\n",
+			fileName: "bin/updateGit-linux-amd64",
+			expected: checksum,
+		},
+		{
+			name:      "no match",
+			content:   checksum + "  bin/updateGit-linux-amd64\n",
+			fileName:  "bin/updateGit-darwin-arm64",
+			expectErr: true,
+		},
+		{
+			name: "multiple files",
+			content: otherChecksum + "  bin/updateGit-linux-arm64\n" +
+				checksum + "  bin/updateGit-darwin-arm64\n" +
+				otherChecksum + "  bin/updateGit.sbom.json\n",
+			fileName: "bin/updateGit-darwin-arm64",
+			expected: checksum,
+		},
+		{
+			name:     "extra whitespace around fields",
+			content:  "   " + checksum + " \t  bin/updateGit-linux-amd64   \n",
+			fileName: "bin/updateGit-linux-amd64",
+			expected: checksum,
+		},
+		{
+			name:      "empty input",
+			content:   "",
+			fileName:  "bin/updateGit-linux-amd64",
+			expectErr: true,
+		},
+		{
+			name:     "windows line endings",
+			content:  otherChecksum + "  bin/updateGit-linux-arm64\r\n" + checksum + "  bin/updateGit-linux-amd64\r\n",
+			fileName: "bin/updateGit-linux-amd64",
+			expected: checksum,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseChecksum(tt.content, tt.fileName)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected an error, got checksum %q", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected checksum %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestDownloadFileWithRetry(t *testing.T) {
+	tests := []struct {
+		name             string
+		failures         int
+		failureStatus    int
+		maxRetries       int
+		expectErr        bool
+		expectedRequests int
+	}{
+		{name: "success after 5xx responses", failures: 2, failureStatus: http.StatusServiceUnavailable, maxRetries: 3, expectedRequests: 3},
+		{name: "5xx responses exceed retries", failures: 5, failureStatus: http.StatusBadGateway, maxRetries: 2, expectErr: true, expectedRequests: 3},
+		{name: "4xx response is not retried", failures: 1, failureStatus: http.StatusNotFound, maxRetries: 3, expectErr: true, expectedRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= tt.failures {
+					w.WriteHeader(tt.failureStatus)
+					return
+				}
+				w.Write([]byte("binary"))
+			}))
+			defer server.Close()
+
+			data, err := DownloadFileWithRetry(server.URL, tt.maxRetries, time.Millisecond)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("DownloadFileWithRetry() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if !tt.expectErr && string(data) != "binary" {
+				t.Errorf("DownloadFileWithRetry() = %q, want %q", data, "binary")
+			}
+			if requests != tt.expectedRequests {
+				t.Errorf("expected %d requests, got %d", tt.expectedRequests, requests)
+			}
+		})
+	}
+}
+
+func TestApplyUpdate(t *testing.T) {
+	oldContent := []byte("#!/bin/sh\necho old\n")
+	newContent := []byte("#!/bin/sh\necho new\n")
+
+	executable := filepath.Join(t.TempDir(), config.CLIName)
+	if err := os.WriteFile(executable, oldContent, config.PermissionBinary); err != nil {
+		t.Fatalf("could not write fake executable: %v", err)
+	}
+	oldExecutable := currentExecutable
+	currentExecutable = func() (string, error) { return executable, nil }
+	t.Cleanup(func() { currentExecutable = oldExecutable })
+
+	assetName := fmt.Sprintf("%s-%s-%s", config.CLIName, runtime.GOOS, runtime.GOARCH)
+	checksum := sha256.Sum256(newContent)
+	checksums := fmt.Sprintf("%s  %s%s\n", hex.EncodeToString(checksum[:]), config.CLICheckSumBinDir, assetName)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + assetName:
+			w.Write(newContent)
+		case "/checksums.txt":
+			w.Write([]byte(checksums))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	release := &GitHubRelease{
+		TagName: "v9.9.9",
+		Assets: []GitHubReleaseAsset{
+			{Name: assetName, DownloadURL: server.URL + "/" + assetName},
+			{Name: "checksums.txt", DownloadURL: server.URL + "/checksums.txt"},
+		},
+	}
+
+	ApplyUpdate(release)
+
+	content, err := os.ReadFile(executable)
+	if err != nil {
+		t.Fatalf("could not read updated executable: %v", err)
+	}
+	if string(content) != string(newContent) {
+		t.Errorf("expected the new binary at %s, got %q", executable, content)
+	}
+	info, err := os.Stat(executable)
+	if err != nil {
+		t.Fatalf("could not stat updated executable: %v", err)
+	}
+	if info.Mode().Perm() != config.PermissionBinary {
+		t.Errorf("expected permission %v of the new binary, got %v", config.PermissionBinary, info.Mode().Perm())
+	}
+
+	oldBinary, err := os.ReadFile(executable + ".old")
+	if err != nil {
+		t.Fatalf("expected the old binary to be renamed: %v", err)
+	}
+	if string(oldBinary) != string(oldContent) {
+		t.Errorf("unexpected content of the old binary: %q", oldBinary)
+	}
+
+	// The lock and the temporary file are removed
+	if _, err := os.Stat(updateLockPath(executable)); !os.IsNotExist(err) {
+		t.Errorf("expected the update lock to be removed, got %v", err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(executable), "update-*.tmp")); len(matches) > 0 {
+		t.Errorf("expected no temporary files, got %v", matches)
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"runtime"
+
 	"github.com/aeciopires/updateGit/cmd"
 	"github.com/aeciopires/updateGit/internal/common"
 	"github.com/aeciopires/updateGit/internal/config"
@@ -8,7 +10,9 @@ import (
 )
 
 func main() {
-	getinfo.CheckOperatingSystem()
+	if err := getinfo.CheckOperatingSystem(runtime.GOOS); err != nil {
+		common.Logger("fatal", "%v", err)
+	}
 	common.CheckCommandsAvailable(config.CommandsToCheck)
 	cmd.Execute()
 }